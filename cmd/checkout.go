@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 
 	"github.com/skatteetaten/ao/pkg/auroraconfig"
 	"github.com/spf13/cobra"
@@ -14,6 +15,10 @@ import (
 var checkoutCmd = &cobra.Command{
 	Use:   "checkout",
 	Short: "Checkout AuroraConfig (git repository) for current affiliation",
+	Long: `Clones the AuroraConfig git repository for the affiliation.  The password is asked for if the server requires it.
+
+The git commands in ao (checkout, pull and save) do not need git to be installed.  Set AO_GIT_COMMAND=true to use
+the git command instead, for instance to use the credential helpers configured for git.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		affiliation := config.GetAffiliation()
 
@@ -28,12 +33,18 @@ var checkoutCmd = &cobra.Command{
 			path = fmt.Sprintf("%s/%s", wd, affiliation)
 		}
 
+		if files, err := ioutil.ReadDir(path); err == nil && len(files) > 0 {
+			fmt.Println("The path " + path + " already exists and is not empty")
+			return
+		}
+
 		url := getGitUrl(affiliation, userName)
 
 		fmt.Printf("Cloning AuroraConfig for affiliation %s\n", affiliation)
 		fmt.Printf("From: %s\n\n", url)
 
 		if output, err := auroraconfig.Checkout(url, path); err != nil {
+			fmt.Println(err)
 			return
 		} else {
 			fmt.Print(output)
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Completion is given up rather than keeping the shell waiting for a server that does not answer
+const completeTimeout = 2 * time.Second
+
+// The command called by the completion scripts from ao completion.  The arguments are the words on the command line
+// after ao, the last one being the word that is completed, and the candidates are printed one per line, with an
+// optional description after a tab.
+var completeCmd = &cobra.Command{
+	Use:                "__complete",
+	Short:              "Lists the completions for a command line",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		completeFromCache = true
+		serverapi.RequestTimeout = completeTimeout
+		for _, suggestion := range completeCommandLine(args) {
+			if suggestion.Description == "" {
+				fmt.Println(suggestion.Text)
+			} else {
+				fmt.Println(suggestion.Text + "\t" + suggestion.Description)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(completeCmd)
+}
+
+func completeCommandLine(args []string) []prompt.Suggest {
+	current := ""
+	words := args
+	if len(args) > 0 {
+		current = args[len(args)-1]
+		words = args[:len(args)-1]
+	}
+
+	var suggestions []prompt.Suggest
+	if strings.HasPrefix(current, "-") {
+		cmd, _, err := RootCmd.Find(words)
+		if err != nil {
+			return nil
+		}
+		suggestions = flagSuggestions(cmd)
+	} else {
+		suggestions = dynamicSuggestions(words)
+		if cmd, args, err := RootCmd.Find(words); err == nil && cmd.HasAvailableSubCommands() {
+			if positional, flag := parseCompletionArgs(cmd, args); len(positional) == 0 && flag == nil {
+				suggestions = append(subcommandSuggestions(cmd), suggestions...)
+			}
+		}
+	}
+
+	var matching []prompt.Suggest
+	for _, suggestion := range suggestions {
+		if strings.HasPrefix(suggestion.Text, current) {
+			matching = append(matching, suggestion)
+		}
+	}
+	return matching
+}
+
+func subcommandSuggestions(cmd *cobra.Command) []prompt.Suggest {
+	var suggestions []prompt.Suggest
+	for _, subcommand := range cmd.Commands() {
+		if subcommand.IsAvailableCommand() {
+			suggestions = append(suggestions, prompt.Suggest{Text: subcommand.Name(), Description: subcommand.Short})
+		}
+	}
+	return suggestions
+}
+
+func flagSuggestions(cmd *cobra.Command) []prompt.Suggest {
+	var suggestions []prompt.Suggest
+	addFlag := func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			suggestions = append(suggestions, prompt.Suggest{Text: "--" + flag.Name, Description: flag.Usage})
+		}
+	}
+	cmd.Flags().VisitAll(addFlag)
+	cmd.InheritedFlags().VisitAll(addFlag)
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Text < suggestions[j].Text
+	})
+	return suggestions
+}
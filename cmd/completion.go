@@ -2,31 +2,90 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
+const bashCompletion = `# bash completion for ao
+_ao_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local candidates
+    candidates=$(ao __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1)
+    COMPREPLY=( $(compgen -W "${candidates}" -- "${cur}") )
+}
+complete -o default -F _ao_complete ao
+`
+
+const zshCompletion = `#compdef ao
+# zsh completion for ao
+_ao() {
+    local -a candidates
+    local line name description
+    for line in "${(@f)$(ao __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z "$line" ]] && continue
+        name="${line%%$'\t'*}"
+        description=""
+        [[ "$line" == *$'\t'* ]] && description="${line#*$'\t'}"
+        candidates+=("${name//:/\\:}:${description}")
+    done
+    _describe 'ao' candidates
+}
+compdef _ao ao
+`
+
+const fishCompletion = `# fish completion for ao
+function __ao_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    ao __complete $tokens (commandline -ct) 2>/dev/null
+end
+complete -c ao -f -a '(__ao_complete)'
+`
+
 var completionCmd = &cobra.Command{
-	Use:   "completion",
-	Short: "Generates bash completion file",
-	Long: `This command generates a bash script file that provides bash completion.
-Bash completion allows you to press the Tab key to complete keywords.
-After running this command, a file called ao.sh will exist in your home directory.
-By typing
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generates shell completion scripts",
+	Long: `This command generates a script that provides completion for bash, zsh or fish.
+Completion allows you to press the Tab key to complete commands, flags, files, environments,
+applications, vaults, secrets and clusters.
 
-	source ./ao.sh
+The script for the given shell is printed to standard out:
 
-you will have bash completion in ao
+	source <(ao completion bash)
+	source <(ao completion zsh)
+	ao completion fish | source
 
-To persist this across login sessions, please update your .bashrc file.`,
+To persist this across login sessions, add the line to your .bashrc, .zshrc or config.fish.
+
+Without an argument, a bash script file called ao.sh is created in the current folder.
+
+Files, environments and applications are completed from the AuroraConfig cached on disk by the last ao command
+that read it, so they are fast enough for every Tab press.  After a change is saved, the next completion reads it again.  The names of the vaults and secrets are cached for five minutes.
+If Boober does not answer within two seconds, there are no completions from it.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := RootCmd.GenBashCompletionFile("ao.sh"); err == nil {
-			wd, _ := os.Getwd()
-			fmt.Println("Bash completion file created at", wd+"/ao.sh")
-		} else {
-			fmt.Println(err)
+		if len(args) == 0 {
+			if err := ioutil.WriteFile("ao.sh", []byte(bashCompletion), 0644); err == nil {
+				wd, _ := os.Getwd()
+				fmt.Println("Bash completion file created at", wd+"/ao.sh")
+			} else {
+				fmt.Println(err)
+			}
+			return
+		}
+
+		switch args[0] {
+		case "bash":
+			fmt.Print(bashCompletion)
+		case "zsh":
+			fmt.Print(zshCompletion)
+		case "fish":
+			fmt.Print(fishCompletion)
+		default:
+			fmt.Println("Unknown shell: " + args[0] + ", use bash, zsh or fish")
 		}
 	},
 }
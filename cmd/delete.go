@@ -38,6 +38,9 @@ Specifying the force flag will suppress the confirmation prompts, and delete all
 var deleteAppCmd = &cobra.Command{
 	Use:   "app <appname>",
 	Short: "Delete application",
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetApps",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) != 1 {
@@ -52,6 +55,9 @@ var deleteAppCmd = &cobra.Command{
 var deleteEnvCmd = &cobra.Command{
 	Use:   "env <envname>",
 	Short: "Delete environment",
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetEnvs",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) != 1 {
@@ -66,6 +72,9 @@ var deleteEnvCmd = &cobra.Command{
 var deleteDeploymentCmd = &cobra.Command{
 	Use:   "deployment <envname> <appname>",
 	Short: "Delete deployment",
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetEnvs GetApps",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) != 2 {
@@ -80,6 +89,9 @@ var deleteDeploymentCmd = &cobra.Command{
 var deleteFileCmd = &cobra.Command{
 	Use:   "file <filename>",
 	Short: "Delete file",
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetFiles",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) != 1 {
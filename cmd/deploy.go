@@ -26,10 +26,16 @@ import (
 var appList []string
 var envList []string
 var overrideJson []string
+var overrideFiles []string
+var showEffectiveFlag bool
+var deployExcludes []string
 var deployAllFlag bool
 var forceDeployFlag bool
 var deployVersion string
 var deployAffiliation string
+var deployReportFile string
+var deployCheckVaults bool
+var deployFromLocal string
 
 // deployCmd represents the deploy command
 var deployCmd = &cobra.Command{
@@ -76,10 +82,55 @@ It is also possible to specify the env and app in the form app/env, so the comma
 
 will deploy niceapp in the superapp-test environment.
 
+Deployments can also be selected with patterns and with fields in the configuration:
+
+	ao deploy '*-test/api-*'		Glob pattern matched against env/app
+	ao deploy 'api-*'				Without a slash, the pattern is matched against both env and app names
+	ao deploy '~^(utv|test)/'		Regular expression matched against env/app
+	ao deploy cluster=utv type=deploy	Fields in the merged configuration, all must match
+	ao deploy groups=frontend		For list fields, one of the values must match
+	ao deploy test cluster!=prod	Fields can be negated, and combined with env and app names
+	ao deploy test 'api-*'			Patterns combined with env and app names select the deployments matching both
+	ao deploy --all -x '*-prod/*'	Exclusions, also given as arguments starting with !
+
+When patterns or fields are used, the confirmation dialog will list each env/app that will be deployed.
+
 If the command will result in multiple deploys, a confirmation dialog will be shown, listing the result of the command.
 The list will contain all the affected applications and environments.  Please note that the two columns are not correlated.
 The --force flag will override this, and execute the deploy without confirmation.
 
+Overrides are given with the -o flag in the form [env/]file.json:<json>, or read from a local file with the
+--override-file flag in the form [env/]file.json:<path>.  A JSON object is applied as a RFC 7396 merge patch, and a JSON array
+is applied as a RFC 6902 JSON Patch:
+
+	ao deploy test/api -o 'test/api.json:{"replicas": 3}'
+	ao deploy test/api -o 'test/api.json:[{"op": "remove", "path": "/route"}]'
+	ao deploy test/api --override-file test/api.json:./api-override.json
+
+The file names must end with .json.  The overrides are validated before the deploy is sent.  The --show-effective flag
+will print the resulting files and ask for confirmation before deploying.
+
+The exit code tells the result of the deploy:
+
+	0	All applications were deployed
+	1	The deploy failed
+	2	The configuration or the arguments are not valid
+	3	Some of the applications or clusters failed
+	4	Not authorized, the token was rejected
+
+The --check-vaults flag will check that the vaults and keys referenced by the deployments exist before deploying.
+
+The --report flag writes a JSON report with one entry per deployed application and per error reported by a cluster.
+
+The --from-local flag deploys the files in a checked out AuroraConfig (see ao checkout) instead of the AuroraConfig
+stored in Boober, without saving them.  Boober uses the local files for this deploy only.  The flag takes the path to
+the repository or a folder in it, default the current folder.  The files that differ from Boober are listed first:
+
+	ao deploy test/api --from-local
+	ao deploy test/api --from-local=../my-affiliation
+
+With --version, the version is only changed in the local files sent with the deploy.
+
 `,
 	Aliases: []string{"setup"},
 	Annotations: map[string]string{
@@ -88,26 +139,111 @@ The --force flag will override this, and execute the deploy without confirmation
 	Run: func(cmd *cobra.Command, args []string) {
 		deploy := deploy.DeployClass{
 			Configuration: config,
+			OverrideFiles: overrideFiles,
+			ShowEffective: showEffectiveFlag,
+			Excludes:      deployExcludes,
+			CheckVaults:   deployCheckVaults,
+			FromLocal:     deployFromLocal,
 		}
 
 		output, err := deploy.ExecuteDeploy(args, overrideJson, appList, envList, &persistentOptions, localDryRun, deployAllFlag, forceDeployFlag, deployVersion, deployAffiliation)
+		if output != "" {
+			fmt.Println(output)
+		}
+		l := log.New(os.Stderr, "", 0)
+		if deployReportFile != "" {
+			if reportErr := deploy.WriteReport(deployReportFile, err); reportErr != nil {
+				l.Println("Unable to write report: " + reportErr.Error())
+			}
+		}
 		if err != nil {
-			l := log.New(os.Stderr, "", 0)
 			l.Println(err.Error())
-			os.Exit(-1)
+			os.Exit(deploy.ExitCode())
+		}
+	},
+}
+
+var deployHistoryLimit int
+
+var deployHistoryCmd = &cobra.Command{
+	Use:   "history [env/app]",
+	Short: "List the deploys done from this client",
+	Long: `Every deploy is recorded in a local journal (~/.ao-deploy-history.json) with time, user, affiliation,
+environment, application, version, cluster, operation and overrides.
+This command lists the journal for the current affiliation, newest first.
+If an argument is given, only deployments where env/app contains the argument are listed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		filter := ""
+		if len(args) == 1 {
+			filter = args[0]
+		}
+		affiliation := config.GetAffiliation()
+		if deployAffiliation != "" {
+			affiliation = deployAffiliation
+		}
+
+		if output, err := deploy.History(affiliation, filter, deployHistoryLimit); err == nil {
+			fmt.Println(output)
 		} else {
-			if output != "" {
-				fmt.Println(output)
-			}
+			fmt.Println(err)
+		}
+	},
+}
+
+var deployStatusCmd = &cobra.Command{
+	Use:   "status [env/app]",
+	Short: "Show what is running in each cluster compared to the AuroraConfig",
+	Long: `For each deployment in the AuroraConfig, the command will look up the DeploymentConfig in the target cluster
+and compare the version in the AuroraConfig with the image tag of the running DeploymentConfig.
+If an argument is given, only deployments where env/app contains the argument are shown.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetDeployments",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		filter := ""
+		if len(args) == 1 {
+			filter = args[0]
+		}
+		if deployAffiliation != "" {
+			config.OpenshiftConfig.Affiliation = deployAffiliation
+		}
+
+		if output, err := deploy.Status(filter, config); err == nil {
+			fmt.Println(output)
+		} else {
+			fmt.Println(err)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(deployCmd)
+	deployCmd.AddCommand(deployHistoryCmd)
+	deployCmd.AddCommand(deployStatusCmd)
+
+	deployHistoryCmd.Flags().IntVarP(&deployHistoryLimit, "limit",
+		"n", 20, "Maximum number of entries to list, 0 lists all")
+	deployHistoryCmd.Flags().StringVarP(&deployAffiliation, "affiliation",
+		"", "", "Overrides the logged in affiliation")
+	deployStatusCmd.Flags().StringVarP(&deployAffiliation, "affiliation",
+		"", "", "Overrides the logged in affiliation")
 
 	deployCmd.Flags().StringArrayVarP(&overrideJson, "file",
-		"o", overrideValues, "Override in the form [env/]file:{<json override>}")
+		"o", overrideValues, "Override in the form [env/]file.json:{<json override>}")
+
+	deployCmd.Flags().StringArrayVarP(&overrideFiles, "override-file",
+		"", nil, "Override read from a local file, in the form [env/]file.json:<path>")
+
+	deployCmd.Flags().BoolVarP(&showEffectiveFlag, "show-effective",
+		"", false, "Print the overridden files and ask for confirmation before deploying")
 
 	deployCmd.Flags().BoolVarP(&localDryRun, "localdryrun",
 		"z", false, "Does not initiate API, just prints collected files")
@@ -118,10 +254,15 @@ func init() {
 
 	deployCmd.Flags().StringArrayVarP(&envList, "env",
 		"e", nil, "Only deploy specified environment")
+	deployCmd.Flags().SetAnnotation("app", CallbackAnnotation, []string{"GetApps"})
+	deployCmd.Flags().SetAnnotation("env", CallbackAnnotation, []string{"GetEnvs"})
 
 	deployCmd.Flags().BoolVarP(&deployAllFlag, "all",
 		"", false, "Will deploy all applications in all affiliations in all clusters reachable")
 
+	deployCmd.Flags().StringArrayVarP(&deployExcludes, "exclude",
+		"x", nil, "Exclude deployments matching the pattern or field selector")
+
 	deployCmd.Flags().BoolVarP(&forceDeployFlag, "force",
 		"", false, "Supress prompts")
 
@@ -131,4 +272,14 @@ func init() {
 
 	deployCmd.Flags().StringVarP(&deployAffiliation, "affiliation",
 		"", "", "Overrides the logged in affiliation")
+
+	deployCmd.Flags().BoolVarP(&deployCheckVaults, "check-vaults",
+		"", false, "Check that the referenced vaults and keys exist before deploying")
+
+	deployCmd.Flags().StringVarP(&deployReportFile, "report",
+		"", "", "Write a JSON report of the deploy to the file")
+
+	deployCmd.Flags().StringVarP(&deployFromLocal, "from-local",
+		"", "", "Deploy the files in a checked out AuroraConfig without saving them, default the current folder")
+	deployCmd.Flags().Lookup("from-local").NoOptDefVal = "."
 }
@@ -14,14 +14,22 @@ var editcmdObject = &pkgEditCmd.EditcmdClass{
 }
 
 var editCmd = &cobra.Command{
-	Use:   "edit [env/]file",
+	Use:   "edit [env/]file ...",
 	Short: "Edit a single configuration file or a secret in a vault",
 	Long: `Edit a single configuration file or a secret in a vault.
 The file can be specified using unique shortened name, so given that the file superapp-test/about.json exists, then the command
 
 	ao edit test/about
 
-will edit this file, if there is no other file matching the same shortening.`,
+will edit this file, if there is no other file matching the same shortening.
+
+Several files can be edited in one editor session, given as separate arguments or as a glob pattern:
+
+	ao edit test/api prod/api api.json
+	ao edit '*/api'
+
+The files are opened in one buffer, each file starting with a "--- <filename>" line.  The changes are
+validated together and saved in one AuroraConfig write, so Boober never sees some of the files changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) < 1 {
@@ -39,14 +47,22 @@ will edit this file, if there is no other file matching the same shortening.`,
 }
 
 var editFileCmd = &cobra.Command{
-	Use:   "file [env/]<filename>",
+	Use:   "file [env/]<filename> ...",
 	Short: "Edit a single configuration file",
 	Long: `Edit a single configuration file or a secret in a vault.
 The file can be specified using unique shortened name, so given that the file superapp-test/about.json exists, then the command
 
 	ao edit test/about
 
-will edit this file, if there is no other file matching the same shortening.`,
+will edit this file, if there is no other file matching the same shortening.
+
+Several files can be edited in one editor session, given as separate arguments or as a glob pattern:
+
+	ao edit test/api prod/api api.json
+	ao edit '*/api'
+
+The files are opened in one buffer, each file starting with a "--- <filename>" line.  The changes are
+validated together and saved in one AuroraConfig write, so Boober never sees some of the files changed.`,
 	Annotations: map[string]string{
 		CallbackAnnotation: "GetFiles",
 	},
@@ -71,6 +87,9 @@ var editSecretCmd = &cobra.Command{
 	Long: `This command will edit the content of the given secret in a vault.
 If the given vault does not exist, it will be created.
 If the given secret does not exist in the vault, it will be created.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults GetSecrets",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 2 {
 			cmd.Usage()
@@ -91,6 +110,9 @@ var editVaultCmd = &cobra.Command{
 	Long: `This command will edit the content of the given vault.
 The editor will present a JSON view of the vault.
 The secrets will be presented as Base64 encoded strings.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			cmd.Usage()
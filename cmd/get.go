@@ -7,6 +7,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var getSecretOptions pkgGetCmd.SecretOptions
+
 var getcmdObject = &pkgGetCmd.GetcmdClass{
 	Configuration: config,
 }
@@ -65,6 +67,9 @@ numer of secrets in the vault.
 If a vaultname is specified, the command will list the secrets in the given vault.
 To access a secret, use the get secret command.`,
 	Aliases: []string{"vaults"},
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		var output string
@@ -87,7 +92,13 @@ To access a secret, use the get secret command.`,
 var getSecretCmd = &cobra.Command{
 	Use:   "secret <vault> <secret>",
 	Short: "Get secret",
-	Long:  `The command will print the content of the secret to standard out.`,
+	Long: `The command will show the secret with the values masked.
+Use --reveal to print the content of the secret to standard out.
+Binary secrets, like keystores, are never printed; use --output-file to save them to a file
+that only the owner can read, or --clipboard to copy a text secret to the clipboard.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults GetSecrets",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) != 2 {
@@ -95,7 +106,7 @@ var getSecretCmd = &cobra.Command{
 			return
 		}
 
-		if output, err := getcmdObject.Secret(args[0], args[1]); err == nil {
+		if output, err := getcmdObject.Secret(args[0], args[1], getSecretOptions); err == nil {
 			fmt.Println(output)
 		} else {
 			fmt.Println(err)
@@ -113,6 +124,9 @@ API cluster.
 The Deploy command will send the same request to all the reachable clusters, allowing each to filter deploys
 intended for that particular cluster.`,
 	Aliases: []string{"clusters"},
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetClusters",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		clusterName := ""
 
@@ -164,6 +178,10 @@ func init() {
 	getCmd.AddCommand(getKubeConfigCmd)
 	getCmd.AddCommand(getOcLoginCmd)
 
+	getSecretCmd.Flags().BoolVarP(&getSecretOptions.Reveal, "reveal", "", false, "Print the values of the secret")
+	getSecretCmd.Flags().StringVarP(&getSecretOptions.OutputFile, "output-file", "o", "", "Write the secret to the given file with mode 0600")
+	getSecretCmd.Flags().BoolVarP(&getSecretOptions.Clipboard, "clipboard", "", false, "Copy the secret to the clipboard")
+
 	getClusterCmd.Flags().BoolP("all",
 		"a", false, "Show all clusters, not just the reachable ones")
 }
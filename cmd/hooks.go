@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skatteetaten/ao/pkg/hookscmd"
+	"github.com/spf13/cobra"
+)
+
+var hooksForce bool
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Git hooks that check the syntax of a checked out AuroraConfig",
+	Long: `Git hooks for an AuroraConfig checked out with ao checkout, so that errors are found when committing
+instead of when running ao save.
+
+The pre-commit hook checks the staged files, and the pre-push hook checks the files in the pushed commits.
+The hooks check the JSON syntax of the files, and the commit or push is stopped if there are errors, listed by
+file and line.  The files are not sent to Boober, so the AuroraConfig is validated there when it is saved.`,
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the pre-commit and pre-push hooks in the current repository",
+	Run: func(cmd *cobra.Command, args []string) {
+		hooks := hookscmd.HookscmdClass{Configuration: config}
+		output, err := hooks.Install(hooksForce)
+		fmt.Print(output)
+		if err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the hooks installed by ao from the current repository",
+	Run: func(cmd *cobra.Command, args []string) {
+		hooks := hookscmd.HookscmdClass{Configuration: config}
+		output, err := hooks.Uninstall()
+		fmt.Print(output)
+		if err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:    "run <pre-commit|pre-push>",
+	Short:  "Runs a hook, called by git",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println(cmd.UseLine())
+			os.Exit(1)
+		}
+		hooks := hookscmd.HookscmdClass{Configuration: config}
+		var output string
+		var err error
+		switch args[0] {
+		case "pre-commit":
+			output, err = hooks.PreCommit()
+		case "pre-push":
+			output, err = hooks.PrePush(os.Stdin)
+		default:
+			fmt.Fprintln(os.Stderr, "Unknown hook: "+args[0])
+			os.Exit(1)
+		}
+
+		fmt.Fprint(os.Stderr, output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	hooksCmd.AddCommand(hooksRunCmd)
+
+	hooksInstallCmd.Flags().BoolVarP(&hooksForce, "force", "f", false, "Replace existing hooks, they are kept as <hook>.orig")
+}
@@ -37,6 +37,9 @@ It is possible to switch API cluster by using the --apicluster flag.
 The login command will check for available updates.  The --do-update option will make login do the update if
 one is available.
 `,
+	Annotations: map[string]string{
+		CallbackAnnotation: completeFlagsOnly,
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		var affiliation string
 		if len(args) != 1 {
@@ -77,5 +80,6 @@ func init() {
 	loginCmd.Flags().BoolVarP(&recreateConfig, "recreate-config", "", false, "Removes current cluster config and recreates")
 	loginCmd.Flags().BoolVarP(&useCurrentOcLogin, "use-current-oclogin", "", false, "Recreates config based on current OC login")
 	loginCmd.Flags().StringVarP(&apiCluster, "apicluster", "a", "", "Set a specific API cluster to use")
+	loginCmd.Flags().SetAnnotation("apicluster", CallbackAnnotation, []string{"GetClusters"})
 	loginCmd.Flags().BoolVarP(&doUpdate, "do-update", "", false, "Do an update if available")
 }
@@ -45,6 +45,9 @@ If the folder is not empty, the command will return an error.
 If the generate-app is set to false, the generator will not be called, and the command will generate a set of Aurora config files based upon the arguments.
 
 If the artifactid is not given, it will default to the appname.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: completeFlagsOnly,
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		newappcmdObject := newappcmd.NewappcmdClass{Configuration: config}
 		output, err := newappcmdObject.NewappCommand(args, newappArtifactId, newappCluster, newappEnv, newappGroupId, newappFolder, newappOutputfolder, newappType, newappVersion, newappGenerateApp, &persistentOptions)
@@ -82,4 +85,7 @@ func init() {
 	newAppCmd.Flags().StringVarP(&newappCluster, "cluster", "c", "", "OpenShift Clustername target, defaults to AOC API cluster")
 	newAppCmd.Flags().StringVarP(&newappEnv, "env", "e", viper.GetString("USER"), "Environment folder for the config, defaults to username")
 	newAppCmd.Flags().StringVarP(&newappOutputfolder, "output-folder", "o", "", "If specified the files are generated in this folder instead of being sent to Boober")
+
+	newAppCmd.Flags().SetAnnotation("type", CallbackAnnotation, []string{"development|deploy"})
+	newAppCmd.Flags().SetAnnotation("cluster", CallbackAnnotation, []string{"GetClusters"})
 }
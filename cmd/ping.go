@@ -13,6 +13,9 @@ var pingCmd = &cobra.Command{
 	Short: "Checks for open connectivity from all nodes in the cluster to a specific ip address and port. ",
 	Long: `Invokes the network debug service in the Aurora Console
 to ping the specified address and port from each node.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: completeFlagsOnly,
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		pingPort, _ := cmd.Flags().GetString("port")
@@ -36,4 +39,5 @@ func init() {
 
 	pingCmd.Flags().StringP("port", "p", "80", "Port to ping")
 	pingCmd.Flags().StringP("cluster", "c", "", "OpenShift source cluster")
+	pingCmd.Flags().SetAnnotation("cluster", CallbackAnnotation, []string{"GetClusters"})
 }
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/deploy"
+	"github.com/spf13/cobra"
+)
+
+var rolloutStages string
+var rolloutVersion string
+var rolloutTimeout time.Duration
+var rolloutProbeUrl string
+var rolloutStateFile string
+var rolloutResume bool
+var rolloutAffiliation string
+
+var rolloutCmd = &cobra.Command{
+	Use:   "rollout <app> --stages <env>,<env>,...",
+	Short: "Deploy an application stage by stage across environments",
+	Long: `Deploy an application to one environment at a time, in the order given by --stages.
+The stage names may be shortened in the same way as environments in the deploy command.
+
+After each stage, the command waits until the DeploymentConfig in the target cluster is running the configured
+version with all replicas updated and available.  Before the next stage is started, the health probe given by
+--probe-url must return a 2xx status.  The strings {env} and {app} in the URL are replaced by the environment and
+application of the completed stage.  Without a probe, the user is asked to confirm.
+
+If a stage fails, the rollout stops and a report is printed.  The state of each stage is saved in a state file
+(.ao-rollout-<app>.json in the current folder by default), and the rollout can be continued later with --resume:
+
+	ao rollout api --stages test,qa,prod -v 1.2.0
+	ao rollout api --resume
+`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetDeployments",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || (rolloutStages == "" && !rolloutResume) {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		if rolloutAffiliation != "" {
+			config.OpenshiftConfig.Affiliation = rolloutAffiliation
+		}
+
+		var stages []string
+		for _, stage := range strings.Split(rolloutStages, ",") {
+			if stage = strings.TrimSpace(stage); stage != "" {
+				stages = append(stages, stage)
+			}
+		}
+		stateFile := rolloutStateFile
+		if stateFile == "" {
+			stateFile = deploy.DefaultRolloutStateFile(args[0])
+		}
+
+		rollout := deploy.RolloutClass{
+			Configuration: config,
+			StateFile:     stateFile,
+			Timeout:       rolloutTimeout,
+			ProbeUrl:      rolloutProbeUrl,
+		}
+		output, err := rollout.ExecuteRollout(args[0], stages, rolloutVersion, rolloutResume, &persistentOptions)
+		if output != "" {
+			fmt.Println(output)
+		}
+		if err != nil {
+			l := log.New(os.Stderr, "", 0)
+			l.Println(err.Error())
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rolloutCmd)
+
+	rolloutCmd.Flags().StringVarP(&rolloutStages, "stages",
+		"s", "", "Comma separated list of environments, in rollout order")
+	rolloutCmd.Flags().StringVarP(&rolloutVersion, "version",
+		"v", "", "Will update the version tag before each stage is deployed")
+	rolloutCmd.Flags().DurationVarP(&rolloutTimeout, "timeout",
+		"", 10*time.Minute, "Maximum time to wait for each stage to become healthy and to pass the health probe")
+	rolloutCmd.Flags().StringVarP(&rolloutProbeUrl, "probe-url",
+		"", "", "Health probe that must return 2xx before the next stage, {env} and {app} are replaced")
+	rolloutCmd.Flags().StringVarP(&rolloutStateFile, "state-file",
+		"", "", "File to save the stage state in, default .ao-rollout-<app>.json")
+	rolloutCmd.Flags().BoolVarP(&rolloutResume, "resume",
+		"", false, "Continue a stopped rollout from the state file")
+	rolloutCmd.Flags().StringVarP(&rolloutAffiliation, "affiliation",
+		"", "", "Overrides the logged in affiliation")
+}
@@ -44,7 +44,7 @@ This application has two main parts.
 2. apply the aoc configuration to the clusters
 `,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		commandsWithoutLogin := []string{"login", "logout", "version", "update", "help", "deploy"}
+		commandsWithoutLogin := []string{"login", "logout", "version", "update", "help", "deploy", "completion", "__complete", "hooks", "workspace"}
 
 		commands := strings.Split(cmd.CommandPath(), " ")
 		if len(commands) > 1 {
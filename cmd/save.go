@@ -9,12 +9,25 @@ import (
 )
 
 var saveCmd = &cobra.Command{
-	Use:   "save",
+	Use:   "save [paths]",
 	Short: "Save changed, new and deleted files for AuroraConfig",
+	Long: `Saves the changed, new and deleted JSON files in the checked out AuroraConfig to Boober, and updates the
+working copy with the commit Boober makes.  The branch the current branch tracks must be up to date, use ao pull first.
+
+If paths are given, only the files in those paths are saved:
+
+	ao save test/api.json
+	ao save test
+
+Files ignored by git and hidden files, like editor swap files, are not saved.  Changes that are not saved are kept.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		user, _ := cmd.Flags().GetString("user")
 		url := getGitUrl(config.GetAffiliation(), user)
-		if _, err := auroraconfig.Save(url, config); err != nil {
+		output, err := auroraconfig.Save(url, args, config)
+		if output != "" {
+			fmt.Print(output)
+		}
+		if err != nil {
 			fmt.Println(err.Error())
 		} else {
 			fmt.Println("Save success")
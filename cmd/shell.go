@@ -1,16 +1,57 @@
 package cmd
 
 import (
-	"fmt"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/c-bata/go-prompt"
 	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/serverapi"
 	"github.com/spf13/cobra"
-	"sort"
-	"strings"
-	"encoding/json"
+	"github.com/spf13/pflag"
 	"github.com/stromland/cobra-prompt"
 )
 
+/*
+The completions in the shell are driven by the CallbackAnnotation on each command.  The annotation is a space separated
+list of completion kinds, one for each positional argument, where the last kind is used for the rest of the arguments:
+
+	CallbackAnnotation: "GetVaults GetSecrets"
+
+Flag values are completed with the same annotation on the flag:
+
+	cmd.Flags().SetAnnotation("app", CallbackAnnotation, []string{"GetApps"})
+
+A kind that is not one of the kinds below is a list of values separated by |, like "text|json".
+
+The shell only asks for completions on commands that have the annotation, so a command that only completes flag
+values uses completeFlagsOnly, which gives no suggestions for the arguments.
+*/
+
+const (
+	completeFiles       = "GetFiles"
+	completeDeployments = "GetDeployments"
+	completeEnvs        = "GetEnvs"
+	completeApps        = "GetApps"
+	completeVaults      = "GetVaults"
+	completeSecrets     = "GetSecrets"
+	completeClusters    = "GetClusters"
+	completeFlagsOnly   = "-"
+)
+
+// The suggestions are kept until something is written to Boober, or for suggestionMaxAge
+const suggestionMaxAge = 30 * time.Second
+
+type cachedSuggestions struct {
+	suggestions []prompt.Suggest
+	generation  int
+	created     time.Time
+}
+
+var suggestionCache = make(map[string]cachedSuggestions)
+
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Interactive shell",
@@ -32,99 +73,291 @@ func init() {
 	RootCmd.AddCommand(shellCmd)
 }
 
-func handleSuggestions(annotation string, _ prompt.Document) []prompt.Suggest {
+func handleSuggestions(_ string, document prompt.Document) []prompt.Suggest {
+	words := strings.Fields(document.TextBeforeCursor())
+	// The word being typed is not complete, and is filtered on by cobra-prompt
+	if document.GetWordBeforeCursor() != "" && len(words) > 0 {
+		words = words[:len(words)-1]
+	}
+	return dynamicSuggestions(words)
+}
+
+// Returns the values for the next argument or flag value after the given words, using the CallbackAnnotation
+func dynamicSuggestions(words []string) []prompt.Suggest {
+	cmd, args, err := RootCmd.Find(words)
+	if err != nil {
+		return nil
+	}
+	positional, flag := parseCompletionArgs(cmd, args)
+	if flag != nil {
+		if kinds := flag.Annotations[CallbackAnnotation]; len(kinds) > 0 {
+			return getSuggestions(kinds[0], positional)
+		}
+		return nil
+	}
+
+	kinds := strings.Fields(cmd.Annotations[CallbackAnnotation])
+	if len(kinds) == 0 {
+		return nil
+	}
+	index := len(positional)
+	if index >= len(kinds) {
+		index = len(kinds) - 1
+	}
+	return getSuggestions(kinds[index], positional)
+}
+
+func lookupFlag(cmd *cobra.Command, arg string) *pflag.Flag {
+	if strings.HasPrefix(arg, "--") {
+		name := strings.TrimPrefix(arg, "--")
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			return flag
+		}
+		return cmd.InheritedFlags().Lookup(name)
+	}
+	name := strings.TrimPrefix(arg, "-")
+	if len(name) != 1 {
+		return nil
+	}
+	if flag := cmd.Flags().ShorthandLookup(name); flag != nil {
+		return flag
+	}
+	return cmd.InheritedFlags().ShorthandLookup(name)
+}
+
+// Returns the positional arguments, and the flag whose value is being typed, if any
+func parseCompletionArgs(cmd *cobra.Command, args []string) (positional []string, pendingFlag *pflag.Flag) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		if strings.Contains(arg, "=") {
+			continue
+		}
+		flag := lookupFlag(cmd, arg)
+		if flag == nil || flag.NoOptDefVal != "" {
+			continue
+		}
+		if i == len(args)-1 {
+			return positional, flag
+		}
+		i++
+	}
+	return positional, nil
+}
+
+func getSuggestions(kind string, positional []string) []prompt.Suggest {
+	switch kind {
+	case completeFiles:
+		return cached(kind, getFiles)
+	case completeDeployments:
+		return cached(kind, getDeployments)
+	case completeEnvs:
+		return cached(kind, getEnvs)
+	case completeApps:
+		return cached(kind, getApps)
+	case completeVaults:
+		return cached(kind, getVaults)
+	case completeSecrets:
+		if len(positional) == 0 {
+			return nil
+		}
+		vaultName := positional[0]
+		return cached(kind+":"+vaultName, func() []prompt.Suggest {
+			return getSecrets(vaultName)
+		})
+	case completeClusters:
+		return getClusters()
+	}
+
 	var suggestions []prompt.Suggest
+	if strings.Contains(kind, "|") {
+		for _, value := range strings.Split(kind, "|") {
+			suggestions = append(suggestions, prompt.Suggest{Text: value})
+		}
+	}
+	return suggestions
+}
 
-	switch annotation {
-	case "GetFiles":
-		return getFiles()
-	case "GetDeployments":
-		return getDeployments()
-	default:
-		return suggestions
+func cached(key string, build func() []prompt.Suggest) []prompt.Suggest {
+	entry, found := suggestionCache[key]
+	if found && entry.generation == auroraconfig.CacheGeneration() && time.Since(entry.created) < suggestionMaxAge {
+		return entry.suggestions
 	}
+	entry = cachedSuggestions{suggestions: build(), generation: auroraconfig.CacheGeneration(), created: time.Now()}
+	suggestionCache[key] = entry
+	return entry.suggestions
 }
 
 type AuroraConfigFile struct {
 	Version string `json:"version"`
 }
 
-var acs []prompt.Suggest
+// Set by the __complete command, where a stale AuroraConfig or vault list is better than waiting for Boober
+// on every tab press
+var completeFromCache bool
 
+func loadAuroraConfig() (serverapi.AuroraConfig, error) {
+	if completeFromCache {
+		return auroraconfig.GetCachedAuroraConfig(config)
+	}
+	return auroraconfig.GetAuroraConfig(config)
+}
+
+// Lists the env/app deployments in the AuroraConfig, with the version from the env file or the app file
 func getAuroraConfig() []prompt.Suggest {
-	if len(acs) > 0 {
+	var acs []prompt.Suggest
+	ac, err := loadAuroraConfig()
+	if err != nil {
 		return acs
 	}
-	ac, _ := auroraconfig.GetAuroraConfig(config)
 
 	var keys []string
 	for k := range ac.Files {
 		keys = append(keys, k)
 	}
-
 	sort.Strings(keys)
 
-	versions := make(map[string]string)
-
 	for _, k := range keys {
-		if strings.Contains(k, "about") {
+		if !strings.Contains(k, "/") || strings.HasSuffix(k, "about.json") {
 			continue
 		}
-
 		var file AuroraConfigFile
 		json.Unmarshal(ac.Files[k], &file)
-
-		if file.Version != "" {
-			versions[k] = file.Version
-			if strings.Contains(k, "/") {
-				acs = append(acs, prompt.Suggest{Text: k, Description: file.Version})
-			}
-		} else if strings.Contains(k, "/") {
-			split := strings.Split(k, "/")
-			if versions[split[1]] != "" {
-				acs = append(acs, prompt.Suggest{Text: k, Description: versions[split[1]]})
-			}
-
+		if file.Version == "" {
+			var appFile AuroraConfigFile
+			json.Unmarshal(ac.Files[strings.Split(k, "/")[1]], &appFile)
+			file.Version = appFile.Version
 		}
+		acs = append(acs, prompt.Suggest{Text: k, Description: file.Version})
 	}
 
 	return acs
 }
 
-var filesSuggestions []prompt.Suggest
-
 func getFiles() []prompt.Suggest {
-	if len(filesSuggestions) > 0 {
-		return filesSuggestions
-	}
+	var filesSuggestions []prompt.Suggest
 
-	files, err := auroraconfig.GetFileList(config)
-	sort.Strings(files)
+	ac, err := loadAuroraConfig()
 	if err != nil {
-		fmt.Println(err)
 		return filesSuggestions
 	}
+	var files []string
+	for filename := range ac.Files {
+		files = append(files, filename)
+	}
+	sort.Strings(files)
 
-	for _, f := range files[1:] {
+	for _, f := range files {
 		filesSuggestions = append(filesSuggestions, prompt.Suggest{Text: f})
 	}
 
 	return filesSuggestions
 }
 
-var deploymentSuggestions []prompt.Suggest
-
 func getDeployments() []prompt.Suggest {
-	if len(deploymentSuggestions) > 0 {
-		return deploymentSuggestions
+	var deploymentSuggestions []prompt.Suggest
+
+	for _, s := range getAuroraConfig() {
+		s.Text = strings.TrimSuffix(s.Text, ".json")
+		deploymentSuggestions = append(deploymentSuggestions, s)
 	}
 
+	return deploymentSuggestions
+}
+
+func getEnvs() []prompt.Suggest {
+	var envSuggestions []prompt.Suggest
+	envs := make(map[string]bool)
 	for _, s := range getAuroraConfig() {
-		if strings.Contains(s.Text, "/") && !strings.Contains(s.Text, "about") {
-			s.Text = strings.TrimSuffix(s.Text, ".json")
-			deploymentSuggestions = append(deploymentSuggestions, s)
+		env := strings.Split(s.Text, "/")[0]
+		if !envs[env] {
+			envs[env] = true
+			envSuggestions = append(envSuggestions, prompt.Suggest{Text: env})
 		}
 	}
+	return envSuggestions
+}
 
-	return deploymentSuggestions
+func getApps() []prompt.Suggest {
+	var appSuggestions []prompt.Suggest
+	apps := make(map[string]bool)
+	for _, s := range getAuroraConfig() {
+		app := strings.TrimSuffix(strings.Split(s.Text, "/")[1], ".json")
+		if !apps[app] {
+			apps[app] = true
+			appSuggestions = append(appSuggestions, prompt.Suggest{Text: app})
+		}
+	}
+	sort.Slice(appSuggestions, func(i, j int) bool {
+		return appSuggestions[i].Text < appSuggestions[j].Text
+	})
+	return appSuggestions
+}
+
+func getVaults() []prompt.Suggest {
+	var vaultSuggestions []prompt.Suggest
+	if completeFromCache {
+		vaultNames, err := auroraconfig.GetCachedVaultNames(config)
+		if err != nil {
+			return vaultSuggestions
+		}
+		for vaultName := range vaultNames {
+			vaultSuggestions = append(vaultSuggestions, prompt.Suggest{Text: vaultName})
+		}
+		sort.Slice(vaultSuggestions, func(i, j int) bool {
+			return vaultSuggestions[i].Text < vaultSuggestions[j].Text
+		})
+		return vaultSuggestions
+	}
+	vaults, err := auroraconfig.GetVaultsArray(config)
+	if err != nil {
+		return vaultSuggestions
+	}
+	for _, vault := range vaults {
+		vaultSuggestions = append(vaultSuggestions, prompt.Suggest{Text: vault.Name})
+	}
+	sort.Slice(vaultSuggestions, func(i, j int) bool {
+		return vaultSuggestions[i].Text < vaultSuggestions[j].Text
+	})
+	return vaultSuggestions
+}
+
+func getSecrets(vaultName string) []prompt.Suggest {
+	var secretSuggestions []prompt.Suggest
+	if completeFromCache {
+		vaultNames, err := auroraconfig.GetCachedVaultNames(config)
+		if err != nil {
+			return secretSuggestions
+		}
+		for _, secretName := range vaultNames[vaultName] {
+			secretSuggestions = append(secretSuggestions, prompt.Suggest{Text: secretName})
+		}
+		return secretSuggestions
+	}
+	vault, err := auroraconfig.GetVault(vaultName, config)
+	if err != nil {
+		return secretSuggestions
+	}
+	for secretName := range vault.Secrets {
+		secretSuggestions = append(secretSuggestions, prompt.Suggest{Text: secretName})
+	}
+	sort.Slice(secretSuggestions, func(i, j int) bool {
+		return secretSuggestions[i].Text < secretSuggestions[j].Text
+	})
+	return secretSuggestions
+}
+
+func getClusters() []prompt.Suggest {
+	var clusterSuggestions []prompt.Suggest
+	for _, cluster := range config.OpenshiftConfig.Clusters {
+		description := ""
+		if cluster.Reachable {
+			description = "reachable"
+		}
+		clusterSuggestions = append(clusterSuggestions, prompt.Suggest{Text: cluster.Name, Description: description})
+	}
+	return clusterSuggestions
 }
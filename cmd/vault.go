@@ -1,25 +1,50 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 
+	"github.com/skatteetaten/ao/pkg/cryptutil"
 	"github.com/skatteetaten/ao/pkg/vault"
 	"github.com/spf13/cobra"
 )
 
 var vaultAddGroup string
-var vaultRemoveGroup string
 var vaultAddUser string
-var vaultRemoveUser string
+
+var vaultAddGroups []string
+var vaultRemoveGroups []string
+var vaultAddUsers []string
+var vaultRemoveUsers []string
+var vaultPermissionsSet bool
+var vaultPermissionsAll bool
 
 var vaultFolder string
+var vaultKeyFile string
+var vaultExportAll bool
+
+var vaultUsageCheck bool
+
+var vaultSyncPrune bool
+var vaultSyncDryRun bool
+
+var vaultMoveForce bool
+var vaultToAffiliation string
+
+var secretFromFile string
+var secretFromLiteral string
+var secretFromStdin bool
+var secretOverwrite bool
 
 // vaultCmd represents the vault command
 var vaultCmd = &cobra.Command{
 	Use:   "vault",
 	Short: "Create and perform operations on a vault",
 	Long: `Usage:
-vault create | edit | delete | permissions <vaultname>.`,
+vault create | edit | delete | permissions | export <vaultname>.
+vault secret add | rm | mv | cp <vaultname> <secretname>.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Usage()
 	},
@@ -39,6 +64,7 @@ If no vaultname is given, the vault will be named the same as the <folder>.`,
 			if len(args) == 1 {
 				vaultname = args[0]
 			}
+			cryptutil.SetKeyFile(vaultKeyFile)
 			vault.CreateVault(vaultname, config, vaultFolder, vaultAddUser, vaultAddGroup)
 		} else {
 			fmt.Println(cmd.UseLine())
@@ -49,6 +75,12 @@ If no vaultname is given, the vault will be named the same as the <folder>.`,
 var vaultEditCmd = &cobra.Command{
 	Use:   "edit <vaultname>",
 	Short: "Edit a vault",
+	Long: `Opens the vault in an editor as a YAML document, with the text secrets decoded.
+Binary secrets are shown as a placeholder, and are kept as long as the placeholder is not changed.
+If the changes are rejected, the document is reopened with the errors as comments.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			cmd.Usage()
@@ -64,12 +96,37 @@ var vaultEditCmd = &cobra.Command{
 }
 
 var vaultPermissionsCmd = &cobra.Command{
-	Use:   "permissions <vaultname>",
+	Use:   "permissions <vaultname> | --all",
 	Short: "Add or remove permissions on a vault",
+	Long: `Without flags, the permissions of the vault are listed.
+
+The --add-group, --remove-group, --add-user and --remove-user flags can be repeated, or given a comma separated list:
+
+	ao vault permissions myvault --add-group team-a,team-b --remove-user olduser
+
+The --set flag replaces all the permissions on the vault with the groups and users given with --add-group and --add-user.
+
+The --all flag lists the permissions of all the vaults in the affiliation as a matrix of vaults and groups/users.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
+		if vaultPermissionsAll {
+			if len(args) != 0 {
+				fmt.Println(cmd.UseLine())
+				return
+			}
+			if output, err := vault.PermissionsMatrix(config); err == nil {
+				fmt.Print(output)
+			} else {
+				fmt.Println(err.Error())
+			}
+			return
+		}
+
 		if len(args) == 1 {
-			if output, err := vault.Permissions(args[0], config, vaultAddGroup, vaultRemoveGroup, vaultAddUser, vaultRemoveUser); err == nil {
+			if output, err := vault.Permissions(args[0], config, vaultAddGroups, vaultRemoveGroups, vaultAddUsers, vaultRemoveUsers, vaultPermissionsSet); err == nil {
 				fmt.Print(output)
 			} else {
 				fmt.Println(err.Error())
@@ -83,6 +140,9 @@ var vaultPermissionsCmd = &cobra.Command{
 var vaultDeleteCmd = &cobra.Command{
 	Use:   "delete <vaultname>",
 	Short: "Delete a vault",
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) == 1 {
@@ -112,10 +172,18 @@ vaultsfolder
 Then the command
 	ao vault import vaultsfolder
 will create 2 vaults: vault1 and vault2.  Vault1 will contain 2 secrets: secretfile1 and secretfile2.
-Vault2 will contain 1 secret: secretfile3.`,
+Vault2 will contain 1 secret: secretfile3.
+
+Secret files may be encrypted with age (https://age-encryption.org), to an X25519 recipient or with a passphrase.
+Files with a .age suffix are decrypted before they are sent, and the suffix is removed from the secret name.
+Other files are sent as they are.
+This also applies to create --folder and sync.
+The keys are read from ~/.ao-keys.txt, or the file given by --key-file.  Each line in the key file is either an age
+identity (AGE-SECRET-KEY-1...) or a passphrase, and the file must only be readable by the current user.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if len(args) == 1 {
+			cryptutil.SetKeyFile(vaultKeyFile)
 			if output, err := vault.ImportVaults(args[0], config); err == nil {
 				fmt.Print(output)
 			} else {
@@ -127,6 +195,289 @@ Vault2 will contain 1 secret: secretfile3.`,
 	},
 }
 
+var vaultExportCmd = &cobra.Command{
+	Use:   "export <vaultname> | --all <folder>",
+	Short: "Exports vaults to a set of folders that can be imported again",
+	Long: `Export writes each vault to a separate folder under <folder>, named the same as the vault.
+Each secret is decoded and written to a file named the same as the secret, and the permissions of the vault
+are written to permissions.json.  The folders and files are only readable by the current user.
+
+The result is the catalog structure read by import, so the commands
+
+	ao vault export --all backup
+	ao vault import backup
+
+will restore all the vaults.  A single vault can be restored with
+	ao vault create -f backup/<vaultname>`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var vaultname, folder string
+		if vaultExportAll && len(args) == 1 {
+			folder = args[0]
+		} else if !vaultExportAll && len(args) == 2 {
+			vaultname = args[0]
+			folder = args[1]
+		} else {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+
+		if output, err := vault.ExportVaults(vaultname, vaultExportAll, folder, config); err == nil {
+			fmt.Print(output)
+		} else {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultUsageCmd = &cobra.Command{
+	Use:   "usage [<vaultname>]",
+	Short: "Lists the files in the AuroraConfig that reference each vault",
+	Long: `Lists the files in the AuroraConfig that reference a vault with the secretVault field, and the keys they use.
+The field is either the name of the vault, or an object with the name and the keys:
+
+	"secretVault": {"name": "myvault", "keys": ["db.password"]}
+
+A key is found if the vault has a secret with that name, or a properties secret that defines the key.
+
+The --check flag reports references to vaults or keys that do not exist, and vaults that are not used.
+The command will exit with an error if any problems are found, so it can be run before a deploy.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		vaultname := ""
+		if len(args) == 1 {
+			vaultname = args[0]
+		}
+
+		output, err := vault.Usage(vaultname, vaultUsageCheck, config)
+		fmt.Print(output)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+var vaultSyncCmd = &cobra.Command{
+	Use:   "sync <catalog>",
+	Short: "Updates the vaults to match a set of folders",
+	Long: `Sync compares the secrets in a set of folders with the vaults, and sends only the differences.
+The catalog has the same structure as for import.  A folder without subfolders is synced as a single vault,
+named the same as the folder.
+
+The added (+), changed (~) and removed (-) secrets are listed by name, the values are never shown.
+Vaults that do not exist are created.  Secrets that are only in the vault are kept, unless the --prune flag is given.
+Vaults that are not in the catalog are never removed.
+The permissions are updated if the folder contains a permissions file.
+
+The --dry-run flag will list the differences without changing anything.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+
+		cryptutil.SetKeyFile(vaultKeyFile)
+		output, err := vault.SyncVaults(args[0], vaultSyncPrune, vaultSyncDryRun, config)
+		fmt.Print(output)
+		if err != nil {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultMvCmd = &cobra.Command{
+	Use:     "mv <vaultname> <newname>",
+	Short:   "Renames a vault",
+	Aliases: []string{"move"},
+	Long: `Renames a vault.  A vault with the new name is created with the same secrets and permissions,
+the secretFolder and secretVault references in the AuroraConfig are changed to the new name,
+and then the old vault is deleted.  A summary is shown before anything is changed.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+
+		output, err := vault.MoveVault(args[0], args[1], vaultMoveForce, config)
+		fmt.Print(output)
+		if err != nil {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultCpCmd = &cobra.Command{
+	Use:     "cp <vaultname> [<newname>] [--to-affiliation <affiliation>]",
+	Short:   "Copies a vault to a new name or to another affiliation",
+	Aliases: []string{"copy"},
+	Long: `Copies a vault with its secrets and permissions.  The copy is created in the affiliation given by
+--to-affiliation, or in the current affiliation if <newname> is given.
+The references in the AuroraConfig are not changed.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 && len(args) != 2 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		newName := ""
+		if len(args) == 2 {
+			newName = args[1]
+		}
+
+		if output, err := vault.CopyVault(args[0], newName, vaultToAffiliation, vaultMoveForce, config); err == nil {
+			fmt.Print(output)
+		} else {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultSecretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Add, remove, move or copy a single secret in a vault",
+	Long: `Usage:
+vault secret add | rm | mv | cp <vaultname> <secretname>.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+	},
+}
+
+var vaultSecretAddCmd = &cobra.Command{
+	Use:   "add <vaultname> <secretname> --from-file <file> | --from-literal <value> | --stdin",
+	Short: "Adds a secret to a vault",
+	Long: `Adds a secret to a vault.  The content is read from a file, given on the command line or read from stdin.
+An existing secret will only be replaced if the --overwrite flag is given.`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		content, err := readSecretContent()
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+
+		if output, err := vault.AddSecret(args[0], args[1], content, secretOverwrite, config); err == nil {
+			fmt.Print(output)
+		} else {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultSecretRmCmd = &cobra.Command{
+	Use:     "rm <vaultname> <secretname>",
+	Short:   "Removes a secret from a vault",
+	Aliases: []string{"remove"},
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults GetSecrets",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+
+		if output, err := vault.RemoveSecret(args[0], args[1], config); err == nil {
+			fmt.Print(output)
+		} else {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultSecretMvCmd = &cobra.Command{
+	Use:     "mv <vaultname> <secretname> <tovaultname> [<newsecretname>]",
+	Short:   "Moves a secret to another vault, or renames it",
+	Aliases: []string{"move"},
+	Long: `Moves a secret to another vault.  If <newsecretname> is given, the secret is renamed.
+To rename a secret within a vault, give the same vault twice:
+
+	ao vault secret mv myvault old.properties myvault new.properties`,
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults GetSecrets GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 3 && len(args) != 4 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		newName := ""
+		if len(args) == 4 {
+			newName = args[3]
+		}
+
+		if output, err := vault.MoveSecret(args[0], args[1], args[2], newName, secretOverwrite, config); err == nil {
+			fmt.Print(output)
+		} else {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+var vaultSecretCpCmd = &cobra.Command{
+	Use:     "cp <vaultname> <secretname> <tovaultname> [<newsecretname>]",
+	Short:   "Copies a secret to another vault",
+	Aliases: []string{"copy"},
+	Annotations: map[string]string{
+		CallbackAnnotation: "GetVaults GetSecrets GetVaults",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 3 && len(args) != 4 {
+			fmt.Println(cmd.UseLine())
+			return
+		}
+		newName := ""
+		if len(args) == 4 {
+			newName = args[3]
+		}
+
+		if output, err := vault.CopySecret(args[0], args[1], args[2], newName, secretOverwrite, config); err == nil {
+			fmt.Print(output)
+		} else {
+			fmt.Println(err.Error())
+		}
+	},
+}
+
+func readSecretContent() (content []byte, err error) {
+	sources := 0
+	for _, given := range []bool{secretFromFile != "", secretFromLiteral != "", secretFromStdin} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, errors.New("Exactly one of --from-file, --from-literal and --stdin must be given")
+	}
+
+	switch {
+	case secretFromFile != "":
+		return ioutil.ReadFile(secretFromFile)
+	case secretFromStdin:
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return []byte(secretFromLiteral), nil
+}
+
 func init() {
 	RootCmd.AddCommand(vaultCmd)
 	vaultCreateCmd.Flags().StringVarP(&vaultFolder, "folder", "f", "", "Creates a vault from a set of secret files")
@@ -136,12 +487,44 @@ func init() {
 	vaultCmd.AddCommand(vaultCreateCmd)
 	vaultCmd.AddCommand(vaultEditCmd)
 
-	vaultPermissionsCmd.Flags().StringVarP(&vaultAddGroup, "add-group", "", "", "Add a group permission to the vault")
-	vaultPermissionsCmd.Flags().StringVarP(&vaultRemoveGroup, "remove-group", "", "", "Remove a group permission from the vault")
-	vaultPermissionsCmd.Flags().StringVarP(&vaultAddUser, "add-user", "", "", "Add a user permission to the vault")
-	vaultPermissionsCmd.Flags().StringVarP(&vaultRemoveUser, "remove-user", "", "", "Remove a user permission from the vault")
+	vaultPermissionsCmd.Flags().StringSliceVarP(&vaultAddGroups, "add-group", "", nil, "Add group permissions to the vault")
+	vaultPermissionsCmd.Flags().StringSliceVarP(&vaultRemoveGroups, "remove-group", "", nil, "Remove group permissions from the vault")
+	vaultPermissionsCmd.Flags().StringSliceVarP(&vaultAddUsers, "add-user", "", nil, "Add user permissions to the vault")
+	vaultPermissionsCmd.Flags().StringSliceVarP(&vaultRemoveUsers, "remove-user", "", nil, "Remove user permissions from the vault")
+	vaultPermissionsCmd.Flags().BoolVarP(&vaultPermissionsSet, "set", "", false, "Replace the permissions with the added groups and users")
+	vaultPermissionsCmd.Flags().BoolVarP(&vaultPermissionsAll, "all", "", false, "List the permissions of all vaults")
 	vaultCmd.AddCommand(vaultPermissionsCmd)
 	vaultCmd.AddCommand(vaultDeleteCmd)
 
 	vaultCmd.AddCommand(vaultImportCmd)
+
+	vaultMvCmd.Flags().BoolVarP(&vaultMoveForce, "force", "f", false, "Do not ask for confirmation")
+	vaultCpCmd.Flags().BoolVarP(&vaultMoveForce, "force", "f", false, "Do not ask for confirmation")
+	vaultCpCmd.Flags().StringVarP(&vaultToAffiliation, "to-affiliation", "", "", "Copy the vault to this affiliation")
+	vaultCmd.AddCommand(vaultMvCmd)
+	vaultCmd.AddCommand(vaultCpCmd)
+
+	vaultCmd.PersistentFlags().StringVarP(&vaultKeyFile, "key-file", "", "", "Key file for encrypted secret files, default ~/.ao-keys.txt")
+
+	vaultExportCmd.Flags().BoolVarP(&vaultExportAll, "all", "", false, "Export all vaults in the affiliation")
+	vaultCmd.AddCommand(vaultExportCmd)
+
+	vaultUsageCmd.Flags().BoolVarP(&vaultUsageCheck, "check", "", false, "Report missing and unused vaults and keys")
+	vaultCmd.AddCommand(vaultUsageCmd)
+
+	vaultSyncCmd.Flags().BoolVarP(&vaultSyncPrune, "prune", "", false, "Remove secrets that are not in the folder")
+	vaultSyncCmd.Flags().BoolVarP(&vaultSyncDryRun, "dry-run", "", false, "List the differences without changing the vaults")
+	vaultCmd.AddCommand(vaultSyncCmd)
+
+	vaultSecretAddCmd.Flags().StringVarP(&secretFromFile, "from-file", "", "", "Read the secret from a file")
+	vaultSecretAddCmd.Flags().StringVarP(&secretFromLiteral, "from-literal", "", "", "Use the given value as the secret")
+	vaultSecretAddCmd.Flags().BoolVarP(&secretFromStdin, "stdin", "", false, "Read the secret from stdin")
+	vaultSecretAddCmd.Flags().BoolVarP(&secretOverwrite, "overwrite", "", false, "Replace the secret if it exists")
+	vaultSecretMvCmd.Flags().BoolVarP(&secretOverwrite, "overwrite", "", false, "Replace the destination secret if it exists")
+	vaultSecretCpCmd.Flags().BoolVarP(&secretOverwrite, "overwrite", "", false, "Replace the destination secret if it exists")
+	vaultSecretCmd.AddCommand(vaultSecretAddCmd)
+	vaultSecretCmd.AddCommand(vaultSecretRmCmd)
+	vaultSecretCmd.AddCommand(vaultSecretMvCmd)
+	vaultSecretCmd.AddCommand(vaultSecretCpCmd)
+	vaultCmd.AddCommand(vaultSecretCmd)
 }
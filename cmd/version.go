@@ -30,6 +30,9 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Shows the version of the aoc client",
 	Long:  `Shows the version of the aoc client application`,
+	Annotations: map[string]string{
+		CallbackAnnotation: completeFlagsOnly,
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		var output string
 		var err error
@@ -77,4 +80,5 @@ func init() {
 	// versionCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	versionCmd.Flags().StringVarP(&outputFormat, "output-format", "o", "text", "filename | json | text")
+	versionCmd.Flags().SetAnnotation("output-format", CallbackAnnotation, []string{"filename|json|text"})
 }
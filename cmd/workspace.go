@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/printutil"
+	"github.com/spf13/cobra"
+)
+
+var workspaceNoFetch bool
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Status and pull for all the checked out AuroraConfigs",
+	Long: `The workspace is the AuroraConfigs checked out with ao checkout, one for each affiliation.
+
+The status of each checkout is one or more of:
+
+	clean		No local changes, and up to date
+	dirty		Changed or staged files that are not committed
+	conflicted	Files with merge conflicts
+	ahead		Local commits that are not pushed
+	behind		New commits in Boober that are not pulled
+
+Files that are not tracked by git are not counted as changes.`,
+}
+
+var workspaceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of each checked out AuroraConfig",
+	Run: func(cmd *cobra.Command, args []string) {
+		if aoConfig == nil || len(aoConfig.CheckoutPaths) == 0 {
+			fmt.Println("No AuroraConfig is checked out, use ao checkout")
+			return
+		}
+
+		var affiliations, paths, states, messages []string
+		for _, status := range auroraconfig.GetWorkspaceStatus(aoConfig.CheckoutPaths, !workspaceNoFetch) {
+			message := ""
+			if status.Err != nil {
+				message = status.Err.Error()
+			}
+			affiliations = append(affiliations, status.Affiliation)
+			paths = append(paths, status.Path)
+			states = append(states, status.State())
+			messages = append(messages, message)
+		}
+		fmt.Print(printutil.FormatTable([]string{"AFFILIATION", "PATH", "STATUS", "MESSAGE"}, affiliations, paths, states, messages))
+	},
+}
+
+var workspacePullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull each checked out AuroraConfig that has no local changes",
+	Run: func(cmd *cobra.Command, args []string) {
+		if aoConfig == nil || len(aoConfig.CheckoutPaths) == 0 {
+			fmt.Println("No AuroraConfig is checked out, use ao checkout")
+			return
+		}
+
+		output, err := auroraconfig.PullWorkspace(aoConfig.CheckoutPaths)
+		fmt.Print(output)
+		if err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceStatusCmd)
+	workspaceCmd.AddCommand(workspacePullCmd)
+
+	workspaceStatusCmd.Flags().BoolVarP(&workspaceNoFetch, "no-fetch", "", false, "Do not fetch from the remotes, behind is then from the last fetch")
+}
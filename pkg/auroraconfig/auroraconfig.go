@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/skatteetaten/ao/pkg/configuration"
 	"github.com/skatteetaten/ao/pkg/fileutil"
@@ -15,6 +16,17 @@ import (
 
 const InvalidConfigurationError = "Invalid configuration"
 
+// Returned when Boober rejects the content that is put.  The text of the error is InvalidConfigurationError,
+// and the messages from Boober are kept so that they can be shown by the field they refer to.
+type ValidationError struct {
+	Message    string
+	ItemErrors []serverapi.ResponseItemError
+}
+
+func (validationError *ValidationError) Error() string {
+	return InvalidConfigurationError
+}
+
 type ClientConfig struct {
 	GitUrlPattern    string `json:"gitUrlPattern"`
 	OpenShiftCluster string `json:"openshiftCluster"`
@@ -296,82 +308,104 @@ func GetVaultsArray(configuration *configuration.ConfigurationClass) (vaults []s
 	return vaults, err
 }
 
+// Returned by GetSecret when the vault or the secret does not exist
+var ErrSecretNotFound = errors.New("No such secret")
+
 func GetSecret(vaultName string, secretName string, configuration *configuration.ConfigurationClass) (output string, version string, err error) {
-	var vaults []serverapi.Vault
-	vaults, err = GetVaultsArray(configuration)
-
-	for vaultindex := range vaults {
-		if vaults[vaultindex].Name == vaultName {
-			decodedSecret, _ := base64.StdEncoding.DecodeString(vaults[vaultindex].Secrets[secretName])
-			output = string(decodedSecret)
-			version = vaults[vaultindex].Versions[secretName]
+	vaults, err := GetVaultsArray(configuration)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, vault := range vaults {
+		if vault.Name != vaultName {
+			continue
+		}
+		secret64, found := vault.Secrets[secretName]
+		if !found {
+			return "", "", ErrSecretNotFound
+		}
+		decodedSecret, err := base64.StdEncoding.DecodeString(secret64)
+		if err != nil {
+			return "", "", errors.New("Error: Secret " + secretName + " in vault " + vaultName + " is not legal base64: " + err.Error())
 		}
+		return string(decodedSecret), vault.Versions[secretName], nil
 	}
-	return
+	return "", "", ErrSecretNotFound
 }
 
+// Returns the AuroraConfig from the cache if it is fresh, or if Boober says it is not modified
 func GetAuroraConfig(configuration *configuration.ConfigurationClass) (auroraConfig serverapi.AuroraConfig, err error) {
 	var apiEndpoint string = "/affiliation/" + configuration.GetAffiliation() + "/auroraconfig"
-	var responses map[string]string
 
-	responses, err = serverapi.CallApi(http.MethodGet, apiEndpoint, "", configuration.GetPersistentOptions().ShowConfig,
-		configuration.GetPersistentOptions().ShowObjects, true, configuration.GetPersistentOptions().Localhost,
-		configuration.GetPersistentOptions().Verbose, configuration.OpenshiftConfig, configuration.GetPersistentOptions().DryRun,
-		configuration.GetPersistentOptions().Debug, configuration.GetPersistentOptions().ServerApi, configuration.GetPersistentOptions().Token)
-	if err != nil {
-		for server := range responses {
-			response, err := serverapi.ParseResponse(responses[server])
-			if err != nil {
-				return auroraConfig, err
-			}
-			if !response.Success {
-				output, err := serverapi.ResponsItems2MessageString(response)
-				if err != nil {
-					return auroraConfig, err
-				}
-				err = errors.New(output)
-				return auroraConfig, err
+	key := cacheKey(configuration)
+	entry := getCacheEntry(key)
+	if entry != nil && time.Since(entry.Fetched) < cacheMaxAge {
+		return copyAuroraConfig(entry.AuroraConfig), nil
+	}
 
+	var etag, lastModified string
+	if entry != nil {
+		etag = entry.ETag
+		lastModified = entry.LastModified
+	}
+	output, etag, lastModified, err := serverapi.CallApiConditional(apiEndpoint, etag, lastModified, configuration)
+	if err == serverapi.ErrNotModified {
+		putCacheEntry(key, entry)
+		return copyAuroraConfig(entry.AuroraConfig), nil
+	}
+	if err != nil {
+		if output == "" {
+			return auroraConfig, err
+		}
+		response, parseErr := serverapi.ParseResponse(output)
+		if parseErr != nil {
+			return auroraConfig, parseErr
+		}
+		if !response.Success {
+			message, parseErr := serverapi.ResponsItems2MessageString(response)
+			if parseErr != nil {
+				return auroraConfig, parseErr
 			}
+			return auroraConfig, errors.New(message)
 		}
-
 		return auroraConfig, err
 	}
 
-	if len(responses) != 1 {
-		err = errors.New("Internal error in GetContent: Response from " + strconv.Itoa(len(responses)))
-		return
+	response, err := serverapi.ParseResponse(output)
+	if err != nil {
+		return auroraConfig, err
 	}
-
-	for server := range responses {
-		response, err := serverapi.ParseResponse(responses[server])
-		if err != nil {
-			return auroraConfig, err
-		}
-		auroraConfig, err = serverapi.ResponseItems2AuroraConfig(response)
-
+	auroraConfig, err = serverapi.ResponseItems2AuroraConfig(response)
+	if err != nil {
+		return auroraConfig, err
 	}
 
-	return auroraConfig, nil
+	putCacheEntry(key, &cacheEntry{ETag: etag, LastModified: lastModified, AuroraConfig: auroraConfig})
+	return copyAuroraConfig(auroraConfig), nil
 }
 
 func PutAuroraConfig(auroraConfig serverapi.AuroraConfig, configuration *configuration.ConfigurationClass) (err error) {
+	_, err = PutAuroraConfigWithValidation(auroraConfig, configuration)
+	return err
+}
+
+// Writes the whole AuroraConfig in one request, and returns the validation messages if Boober rejects it.
+// The versions in the AuroraConfig are sent, so that files changed since they were read are rejected.
+func PutAuroraConfigWithValidation(auroraConfig serverapi.AuroraConfig, configuration *configuration.ConfigurationClass) (validationMessages string, err error) {
 	content, err := json.Marshal(auroraConfig)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	var apiEndpoint = "/affiliation/" + configuration.GetAffiliation() + "/auroraconfig"
 
-	_, err = putContent(apiEndpoint, string(content), "", configuration)
-	if err != nil {
-		return err
-	}
-	return
+	return putContent(apiEndpoint, string(content), "", configuration)
 }
 
 func putContent(apiEndpoint string, content string, version string, configuration *configuration.ConfigurationClass) (validationMessages string, err error) {
 	var responses map[string]string
+	defer InvalidateCache(configuration)
 
 	var versionHeader = make(map[string]string)
 	versionHeader["AuroraConfigFileVersion"] = version
@@ -401,7 +435,8 @@ func putContent(apiEndpoint string, content string, version string, configuratio
 			}
 			if !response.Success {
 				validationMessages, _ := serverapi.ResponsItems2MessageString(response)
-				return validationMessages, errors.New(InvalidConfigurationError)
+				itemErrors, _ := serverapi.ResponseItems2ResponseItemErrors(response)
+				return validationMessages, &ValidationError{Message: response.Message, ItemErrors: itemErrors}
 			}
 		}
 
@@ -433,6 +468,7 @@ func PutVault(vaultname string, vault serverapi.Vault, version string, configura
 
 func deleteContent(apiEndpoint string, version string, configuration *configuration.ConfigurationClass) (validationMessages string, err error) {
 	var responses map[string]string
+	defer InvalidateCache(configuration)
 
 	var versionHeader = make(map[string]string)
 	versionHeader["AuroraConfigFileVersion"] = version
@@ -465,3 +501,9 @@ func DeleteVault(vaultname string, configuration *configuration.ConfigurationCla
 	return deleteContent(apiEndpoint, "", configuration)
 
 }
+
+func DeleteSecret(vaultname string, secretname string, version string, configuration *configuration.ConfigurationClass) (validationMessages string, err error) {
+	var apiEndpoint = "/affiliation/" + configuration.GetAffiliation() + "/vault/" + vaultname + "/secret/" + secretname
+
+	return deleteContent(apiEndpoint, version, configuration)
+}
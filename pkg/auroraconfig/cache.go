@@ -0,0 +1,158 @@
+package auroraconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+	"github.com/spf13/viper"
+)
+
+/*
+The AuroraConfig is cached per API cluster and affiliation, in memory and in a folder in the home directory.
+The cached AuroraConfig is used without asking Boober for cacheMaxAge.  After that it is revalidated with the ETag
+or Last-Modified header from the response it came from, or fetched again if Boober sent neither.
+The cache is cleared after any write to Boober.
+
+For completion, the names of the vaults and their secrets are cached on disk as well.  Boober gives no header to
+revalidate them with, so they are used for vaultCacheMaxAge.  The contents of the secrets are never cached.
+*/
+
+const cacheFolder = "/.ao-cache"
+const cacheMaxAge = 30 * time.Second
+const vaultCacheMaxAge = 5 * time.Minute
+
+type cacheEntry struct {
+	ETag         string                 `json:"etag,omitempty"`
+	LastModified string                 `json:"lastModified,omitempty"`
+	AuroraConfig serverapi.AuroraConfig `json:"auroraConfig"`
+	Fetched      time.Time              `json:"fetched"`
+}
+
+type vaultNamesEntry struct {
+	Fetched time.Time           `json:"fetched"`
+	Vaults  map[string][]string `json:"vaults"`
+}
+
+var memoryCache = make(map[string]*cacheEntry)
+
+var cacheGeneration int
+
+func cacheKey(configuration *configuration.ConfigurationClass) string {
+	return configuration.OpenshiftConfig.APICluster + "-" + configuration.GetAffiliation()
+}
+
+func getCacheLocation(key string) string {
+	viper.BindEnv("HOME")
+	return viper.GetString("HOME") + cacheFolder + "/auroraconfig-" + key + ".json"
+}
+
+func getVaultCacheLocation(key string) string {
+	viper.BindEnv("HOME")
+	return viper.GetString("HOME") + cacheFolder + "/vaults-" + key + ".json"
+}
+
+// The callers may change the maps in the AuroraConfig they get, so the cache hands out copies
+func copyAuroraConfig(auroraConfig serverapi.AuroraConfig) (copied serverapi.AuroraConfig) {
+	copied.Files = make(map[string]json.RawMessage, len(auroraConfig.Files))
+	for filename, content := range auroraConfig.Files {
+		copied.Files[filename] = content
+	}
+	copied.Versions = make(map[string]string, len(auroraConfig.Versions))
+	for filename, version := range auroraConfig.Versions {
+		copied.Versions[filename] = version
+	}
+	return copied
+}
+
+// Returns the entry from memory, or from disk if it has not been read in this process
+func getCacheEntry(key string) *cacheEntry {
+	if entry, found := memoryCache[key]; found {
+		return entry
+	}
+	content, err := ioutil.ReadFile(getCacheLocation(key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// Failing to write the cache only means that the AuroraConfig is fetched again, so errors are ignored
+func putCacheEntry(key string, entry *cacheEntry) {
+	entry.Fetched = time.Now()
+	memoryCache[key] = entry
+	writeCacheFile(getCacheLocation(key), entry)
+}
+
+func writeCacheFile(location string, value interface{}) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(location+".tmp", content, 0600)
+	os.Rename(location+".tmp", location)
+}
+
+// Clears the cached AuroraConfig for the affiliation, in memory and on disk
+func InvalidateCache(configuration *configuration.ConfigurationClass) {
+	cacheGeneration++
+	key := cacheKey(configuration)
+	delete(memoryCache, key)
+	os.Remove(getCacheLocation(key))
+	os.Remove(getVaultCacheLocation(key))
+}
+
+// Changes each time the cache is cleared, so that values derived from the AuroraConfig or the vaults
+// can be refreshed after a write
+func CacheGeneration() int {
+	return cacheGeneration
+}
+
+// Returns the cached AuroraConfig, however old, without asking Boober if it has changed, and only gets it if
+// nothing is cached.  Used for completion, where an AuroraConfig from the last command is good enough
+func GetCachedAuroraConfig(configuration *configuration.ConfigurationClass) (serverapi.AuroraConfig, error) {
+	if entry := getCacheEntry(cacheKey(configuration)); entry != nil {
+		return copyAuroraConfig(entry.AuroraConfig), nil
+	}
+	return GetAuroraConfig(configuration)
+}
+
+// Returns the names of the vaults with the names of their secrets, from the cache if it is recent enough.
+// Used for completion, where the names from a few minutes ago are good enough
+func GetCachedVaultNames(configuration *configuration.ConfigurationClass) (map[string][]string, error) {
+	location := getVaultCacheLocation(cacheKey(configuration))
+	if content, err := ioutil.ReadFile(location); err == nil {
+		var entry vaultNamesEntry
+		if err := json.Unmarshal(content, &entry); err == nil && time.Since(entry.Fetched) < vaultCacheMaxAge {
+			return entry.Vaults, nil
+		}
+	}
+
+	vaults, err := GetVaultsArray(configuration)
+	if err != nil {
+		return nil, err
+	}
+	entry := vaultNamesEntry{Fetched: time.Now(), Vaults: make(map[string][]string)}
+	for _, vault := range vaults {
+		secretNames := make([]string, 0, len(vault.Secrets))
+		for secretName := range vault.Secrets {
+			secretNames = append(secretNames, secretName)
+		}
+		sort.Strings(secretNames)
+		entry.Vaults[vault.Name] = secretNames
+	}
+	writeCacheFile(location, entry)
+	return entry.Vaults, nil
+}
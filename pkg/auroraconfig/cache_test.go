@@ -0,0 +1,134 @@
+package auroraconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/cmdoptions"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/openshift"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCacheEntry(t *testing.T) {
+	home, err := ioutil.TempDir("", "ao_cache_")
+	if err != nil {
+		t.Fatalf("Error creating temporary folder: %v", err.Error())
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	auroraConfig := serverapi.AuroraConfig{
+		Files:    map[string]json.RawMessage{"about.json": json.RawMessage(`{}`)},
+		Versions: map[string]string{"about.json": "1"},
+	}
+	putCacheEntry("utv-paas", &cacheEntry{ETag: `"abc"`, AuroraConfig: auroraConfig})
+	putCacheEntry("utv-other", &cacheEntry{AuroraConfig: auroraConfig})
+	memoryCache = make(map[string]*cacheEntry)
+
+	entry := getCacheEntry("utv-paas")
+	if entry == nil || entry.ETag != `"abc"` || entry.AuroraConfig.Versions["about.json"] != "1" {
+		t.Errorf("getCacheEntry did not read the entry from disk: %v", entry)
+	}
+	if entry := getCacheEntry("utv-other"); entry == nil || time.Since(entry.Fetched) > time.Minute {
+		t.Errorf("getCacheEntry did not read the entry without ETag or Last-Modified from disk: %v", entry)
+	}
+
+	copied := copyAuroraConfig(auroraConfig)
+	copied.Files["new.json"] = json.RawMessage(`{}`)
+	if _, found := auroraConfig.Files["new.json"]; found {
+		t.Errorf("copyAuroraConfig returned the same map")
+	}
+}
+
+func TestGetAuroraConfigNotModified(t *testing.T) {
+	home, err := ioutil.TempDir("", "ao_cache_")
+	if err != nil {
+		t.Fatalf("Error creating temporary folder: %v", err.Error())
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+	defer gock.Off()
+	defer func() { memoryCache = make(map[string]*cacheEntry) }()
+
+	boober := "http://boober.test"
+	config := &configuration.ConfigurationClass{
+		PersistentOptions: &cmdoptions.CommonCommandOptions{},
+		OpenshiftConfig: &openshift.OpenshiftConfig{
+			APICluster:  "utv",
+			Affiliation: "paas",
+			Clusters:    []*openshift.OpenshiftCluster{{Name: "utv", BooberUrl: boober, Reachable: true}},
+		},
+	}
+	auroraConfig := serverapi.AuroraConfig{
+		Files:    map[string]json.RawMessage{"about.json": json.RawMessage(`{}`)},
+		Versions: map[string]string{"about.json": "1"},
+	}
+	entry := &cacheEntry{ETag: `"abc"`, AuroraConfig: auroraConfig}
+	putCacheEntry("utv-paas", entry)
+	entry.Fetched = time.Now().Add(-time.Hour)
+
+	gock.New(boober).
+		Get("/affiliation/paas/auroraconfig").
+		MatchHeader("If-None-Match", `"abc"`).
+		Reply(304)
+
+	cached, err := GetAuroraConfig(config)
+	if err != nil {
+		t.Fatalf("GetAuroraConfig failed: %v", err)
+	}
+	if cached.Versions["about.json"] != "1" {
+		t.Errorf("GetAuroraConfig did not return the cached AuroraConfig: %v", cached)
+	}
+	if !gock.IsDone() {
+		t.Errorf("GetAuroraConfig did not revalidate the cached AuroraConfig")
+	}
+
+	memoryCache = make(map[string]*cacheEntry)
+	if entry := getCacheEntry("utv-paas"); entry == nil || time.Since(entry.Fetched) > time.Minute {
+		t.Errorf("The revalidated entry was not written to disk: %v", entry)
+	}
+}
+
+func TestGetCachedAuroraConfig(t *testing.T) {
+	home, err := ioutil.TempDir("", "ao_cache_")
+	if err != nil {
+		t.Fatalf("Error creating temporary folder: %v", err.Error())
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+	defer gock.Off()
+	defer func() { memoryCache = make(map[string]*cacheEntry) }()
+
+	config := &configuration.ConfigurationClass{
+		PersistentOptions: &cmdoptions.CommonCommandOptions{},
+		OpenshiftConfig: &openshift.OpenshiftConfig{
+			APICluster:  "utv",
+			Affiliation: "paas",
+			Clusters:    []*openshift.OpenshiftCluster{{Name: "utv", BooberUrl: "http://boober.test", Reachable: true}},
+		},
+	}
+	auroraConfig := serverapi.AuroraConfig{
+		Files:    map[string]json.RawMessage{"about.json": json.RawMessage(`{}`)},
+		Versions: map[string]string{"about.json": "1"},
+	}
+	// Boober sent no validators, and the entry is from an earlier process
+	writeCacheFile(getCacheLocation("utv-paas"), &cacheEntry{AuroraConfig: auroraConfig, Fetched: time.Now().Add(-time.Hour)})
+	memoryCache = make(map[string]*cacheEntry)
+	gock.New("http://boober.test").Get("/").Reply(500)
+
+	cached, err := GetCachedAuroraConfig(config)
+	if err != nil || cached.Versions["about.json"] != "1" {
+		t.Errorf("GetCachedAuroraConfig did not return the AuroraConfig from disk: %v %v", cached, err)
+	}
+	if !gock.IsPending() {
+		t.Errorf("GetCachedAuroraConfig asked Boober")
+	}
+}
@@ -0,0 +1,80 @@
+package auroraconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+const aboutFile = "about.json"
+
+// Returns all env/app combinations in the AuroraConfig, sorted
+func GetDeployments(auroraConfig serverapi.AuroraConfig) (deployments []string) {
+	for filename := range auroraConfig.Files {
+		parts := strings.Split(filename, "/")
+		if len(parts) != 2 || parts[1] == aboutFile || !strings.HasSuffix(parts[1], ".json") {
+			continue
+		}
+		deployments = append(deployments, strings.TrimSuffix(filename, ".json"))
+	}
+	sort.Strings(deployments)
+	return deployments
+}
+
+// Merges the files that make up a deployment in the same order as Boober: about.json, <app>.json,
+// <env>/about.json and <env>/<app>.json.  The top level fields of later files override earlier ones.
+func GetDeploymentSpec(auroraConfig serverapi.AuroraConfig, env string, app string) (spec map[string]interface{}, err error) {
+	if _, found := auroraConfig.Files[env+"/"+app+".json"]; !found {
+		return nil, errors.New("No such deployment: " + env + "/" + app)
+	}
+
+	spec = make(map[string]interface{})
+	filenames := []string{aboutFile, app + ".json", env + "/" + aboutFile, env + "/" + app + ".json"}
+	for _, filename := range filenames {
+		content, found := auroraConfig.Files[filename]
+		if !found {
+			continue
+		}
+		var fileMap map[string]interface{}
+		if err := json.Unmarshal(content, &fileMap); err != nil {
+			return nil, errors.New("Illegal JSON in " + filename + ": " + err.Error())
+		}
+		for key, value := range fileMap {
+			spec[key] = value
+		}
+	}
+
+	if _, found := spec["name"]; !found {
+		spec["name"] = app
+	}
+	if _, found := spec["envName"]; !found {
+		spec["envName"] = env
+	}
+	return spec, nil
+}
+
+// Returns a field from a deployment spec as a string, or "" if the field is not set
+func GetSpecField(spec map[string]interface{}, field string) string {
+	return SpecValue2String(spec[field])
+}
+
+// Formats a value from a deployment spec as a string.  Lists are joined with commas.
+func SpecValue2String(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case []interface{}:
+		var values []string
+		for _, v := range typed {
+			values = append(values, SpecValue2String(v))
+		}
+		return strings.Join(values, ",")
+	}
+	bytes, _ := json.Marshal(value)
+	return string(bytes)
+}
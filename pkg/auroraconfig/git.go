@@ -0,0 +1,531 @@
+package auroraconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/howeyc/gopass"
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/ao/pkg/executil"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/index"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/storage"
+)
+
+// The git operations done with go-git
+type nativeGit struct {
+	dir string
+}
+
+// The credentials asked for are kept for the rest of the command, by host
+var gitCredentials = make(map[string]transport.AuthMethod)
+
+func (native nativeGit) open() (*git.Repository, error) {
+	dir := native.dir
+	if dir == "" {
+		dir = "."
+	}
+	repository, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err == git.ErrRepositoryNotExists {
+		return nil, &GitError{Operation: "open", Err: errors.New("not in a checked out AuroraConfig")}
+	}
+	if err != nil {
+		return nil, &GitError{Operation: "open", Err: err}
+	}
+	return repository, nil
+}
+
+// Runs the operation, and asks for the password if the server wants one
+func (native nativeGit) withCredentials(remoteUrl string, operation func(auth transport.AuthMethod) error) error {
+	host := remoteUrl
+	if parsed, err := url.Parse(remoteUrl); err == nil {
+		host = parsed.Host
+	}
+	err := operation(gitCredentials[host])
+	for attempt := 0; attempt < 3; attempt++ {
+		if err != transport.ErrAuthenticationRequired && err != transport.ErrAuthorizationFailed {
+			return err
+		}
+		if !executil.IsInteractive() || !strings.HasPrefix(remoteUrl, "http") {
+			return err
+		}
+		auth, promptErr := promptCredentials(remoteUrl)
+		if promptErr != nil {
+			return promptErr
+		}
+		gitCredentials[host] = auth
+		err = operation(auth)
+	}
+	return err
+}
+
+func promptCredentials(remoteUrl string) (transport.AuthMethod, error) {
+	var username string
+	if parsed, err := url.Parse(remoteUrl); err == nil && parsed.User != nil {
+		username = parsed.User.Username()
+	}
+	if username == "" {
+		fmt.Print("Username for " + remoteUrl + ": ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		username = strings.TrimSpace(line)
+	}
+
+	fmt.Print("Password for " + username + ": ")
+	password, err := gopass.GetPasswdMasked()
+	if err != nil {
+		return nil, err
+	}
+	return &githttp.BasicAuth{Username: username, Password: string(password)}, nil
+}
+
+func (native nativeGit) Clone(url string, path string) (string, error) {
+	err := native.withCredentials(url, func(auth transport.AuthMethod) error {
+		// PlainClone removes what it created if the clone fails, and leaves folders that were already there
+		_, err := git.PlainClone(path, false, &git.CloneOptions{URL: url, Auth: auth, Progress: os.Stdout})
+		return err
+	})
+	if err != nil {
+		return "", &GitError{Operation: "clone", Err: err}
+	}
+	return "", nil
+}
+
+// go-git only pulls into a clean working copy, and moves the branch before it finds out that it is not clean.
+// The pull is therefore done here: a fast-forward only writes the files that changed upstream, so it can be done
+// when other files have local changes.  Diverged branches are merged with the git command.
+func (native nativeGit) Pull() (string, error) {
+	if err := native.Fetch(); err != nil {
+		return "", err
+	}
+	upstream, err := native.Upstream()
+	if err != nil {
+		return "", err
+	}
+	repository, err := native.open()
+	if err != nil {
+		return "", err
+	}
+	head, err := repository.Head()
+	if err != nil {
+		return "", &GitError{Operation: "pull", Err: err}
+	}
+	remote, err := repository.Reference(plumbing.ReferenceName("refs/remotes/"+upstream), true)
+	if err != nil {
+		return "", &GitError{Operation: "pull", Err: errors.Wrap(err, upstream)}
+	}
+	if head.Hash() == remote.Hash() {
+		return "Already up to date\n", nil
+	}
+
+	fastForward, err := isAncestor(repository, head.Hash(), remote.Hash())
+	if err != nil {
+		return "", &GitError{Operation: "pull", Err: err}
+	}
+	if !fastForward {
+		if _, lookErr := exec.LookPath("git"); lookErr == nil {
+			return commandGit{dir: native.dir}.Pull()
+		}
+		return "", &GitError{Operation: "pull", Err: errors.New("the local branch has commits that are not in " + upstream +
+			", merging them needs the git command")}
+	}
+
+	if err := fastForwardTo(repository, head, remote.Hash()); err != nil {
+		return "", &GitError{Operation: "pull", Err: err}
+	}
+	return "Updated to " + remote.Hash().String()[:7] + "\n", nil
+}
+
+// Updates the files that differ between the branch and the commit, and moves the branch to the commit.
+// Nothing is changed if any of those files have local changes.
+func fastForwardTo(repository *git.Repository, head *plumbing.Reference, commitHash plumbing.Hash) error {
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return err
+	}
+	fromTree, err := commitTree(repository, head.Hash())
+	if err != nil {
+		return err
+	}
+	toTree, err := commitTree(repository, commitHash)
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	var changed []string
+	for _, change := range changes {
+		filename := change.To.Name
+		if filename == "" {
+			filename = change.From.Name
+		}
+		if fileStatus, found := status[filename]; found && (fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified) {
+			return errors.New("the local changes to " + filename + " would be overwritten, save or revert them first")
+		}
+		changed = append(changed, filename)
+	}
+
+	root := worktree.Filesystem.Root()
+	idx, err := repository.Storer.Index()
+	if err != nil {
+		return err
+	}
+	for _, filename := range changed {
+		path := filepath.Join(root, filepath.FromSlash(filename))
+		file, err := toTree.File(filename)
+		if err == object.ErrFileNotFound {
+			idx.Remove(filename)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, filename)
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return errors.Wrap(err, filename)
+		}
+		mode, err := file.Mode.ToOSFileMode()
+		if err != nil {
+			mode = 0644
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(content), mode); err != nil {
+			return err
+		}
+	}
+	if err := repository.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+	for _, filename := range changed {
+		if _, err := toTree.File(filename); err == nil {
+			if _, err := worktree.Add(filename); err != nil {
+				return errors.Wrap(err, filename)
+			}
+		}
+	}
+
+	return repository.Storer.SetReference(plumbing.NewHashReference(head.Name(), commitHash))
+}
+
+func commitTree(repository *git.Repository, commitHash plumbing.Hash) (*object.Tree, error) {
+	commit, err := repository.CommitObject(commitHash)
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+func (native nativeGit) Fetch() error {
+	repository, err := native.open()
+	if err != nil {
+		return err
+	}
+	remoteUrl, err := native.RemoteUrl("origin")
+	if err != nil {
+		return err
+	}
+
+	err = native.withCredentials(remoteUrl, func(auth transport.AuthMethod) error {
+		return repository.Fetch(&git.FetchOptions{RemoteName: "origin", Auth: auth})
+	})
+	if err == storage.ErrReferenceHasChanged {
+		// go-git fails to update refs that are only in packed-refs, the git command handles them
+		if _, lookErr := exec.LookPath("git"); lookErr == nil {
+			return commandGit{dir: native.dir}.Fetch()
+		}
+	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return &GitError{Operation: "fetch", Err: err}
+	}
+	return nil
+}
+
+func (native nativeGit) RemoteUrl(remote string) (string, error) {
+	repository, err := native.open()
+	if err != nil {
+		return "", err
+	}
+	gitRemote, err := repository.Remote(remote)
+	if err == git.ErrRemoteNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", &GitError{Operation: "remote", Err: err}
+	}
+	if urls := gitRemote.Config().URLs; len(urls) > 0 {
+		return urls[0], nil
+	}
+	return "", nil
+}
+
+func (native nativeGit) Root() (string, error) {
+	repository, err := native.open()
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return "", &GitError{Operation: "open", Err: err}
+	}
+	return worktree.Filesystem.Root(), nil
+}
+
+func (native nativeGit) Upstream() (string, error) {
+	repository, err := native.open()
+	if err != nil {
+		return "", err
+	}
+	head, err := repository.Head()
+	if err != nil {
+		return "", &GitError{Operation: "rev-parse", Err: err}
+	}
+	if !head.Name().IsBranch() {
+		return "", errors.New("Not on a branch, please check out a branch before saving")
+	}
+	branch := head.Name().Short()
+
+	gitConfig, err := repository.Config()
+	if err != nil {
+		return "", &GitError{Operation: "config", Err: err}
+	}
+	if tracking, found := gitConfig.Branches[branch]; found && tracking.Remote != "" && tracking.Merge != "" {
+		return tracking.Remote + "/" + tracking.Merge.Short(), nil
+	}
+	return "origin/" + branch, nil
+}
+
+func (native nativeGit) Diverged(upstream string) (localCommits bool, remoteCommits bool, err error) {
+	repository, err := native.open()
+	if err != nil {
+		return false, false, err
+	}
+	head, err := repository.Head()
+	if err != nil {
+		return false, false, &GitError{Operation: "log", Err: err}
+	}
+	remote, err := repository.Reference(plumbing.ReferenceName("refs/remotes/"+upstream), true)
+	if err != nil {
+		return false, false, &GitError{Operation: "log", Err: errors.Wrap(err, upstream)}
+	}
+	if head.Hash() == remote.Hash() {
+		return false, false, nil
+	}
+
+	headInRemote, err := isAncestor(repository, head.Hash(), remote.Hash())
+	if err != nil {
+		return false, false, &GitError{Operation: "log", Err: err}
+	}
+	remoteInHead, err := isAncestor(repository, remote.Hash(), head.Hash())
+	if err != nil {
+		return false, false, &GitError{Operation: "log", Err: err}
+	}
+	return !headInRemote, !remoteInHead, nil
+}
+
+func isAncestor(repository *git.Repository, ancestor plumbing.Hash, descendant plumbing.Hash) (found bool, err error) {
+	commit, err := repository.CommitObject(descendant)
+	if err != nil {
+		return false, err
+	}
+	err = object.NewCommitPreorderIter(commit, nil, nil).ForEach(func(c *object.Commit) error {
+		if c.Hash == ancestor {
+			found = true
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return found, err
+}
+
+func inPaths(filename string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, "/")
+		if path == "." || filename == path || strings.HasPrefix(filename, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (native nativeGit) Status(paths []string) (string, error) {
+	repository, err := native.open()
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return "", &GitError{Operation: "status", Err: err}
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return "", &GitError{Operation: "status", Err: err}
+	}
+
+	unmerged, err := unmergedFiles(repository)
+	if err != nil {
+		return "", &GitError{Operation: "status", Err: err}
+	}
+
+	var filenames []string
+	for filename := range status {
+		filenames = append(filenames, filename)
+	}
+	for filename := range unmerged {
+		if _, found := status[filename]; !found {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+
+	var output string
+	for _, filename := range filenames {
+		if code, found := unmerged[filename]; found {
+			if inPaths(filename, paths) {
+				output += code + " " + filename + "\x00"
+			}
+			continue
+		}
+		fileStatus := status[filename]
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		if !inPaths(filename, paths) {
+			continue
+		}
+		output += fmt.Sprintf("%c%c %s\x00", fileStatus.Staging, fileStatus.Worktree, filename)
+		if fileStatus.Staging == git.Renamed || fileStatus.Staging == git.Copied {
+			output += fileStatus.Extra + "\x00"
+		}
+	}
+	return output, nil
+}
+
+// The codes git status --porcelain uses for files with merge conflicts, by the stages the file has in the index
+var unmergedStatusCodes = map[int]string{
+	1<<index.AncestorMode | 1<<index.OurMode | 1<<index.TheirMode: "UU",
+	1<<index.OurMode | 1<<index.TheirMode:                         "AA",
+	1 << index.AncestorMode:                                       "DD",
+	1<<index.AncestorMode | 1<<index.OurMode:                      "UD",
+	1<<index.AncestorMode | 1<<index.TheirMode:                    "DU",
+	1 << index.OurMode:                                            "AU",
+	1 << index.TheirMode:                                          "UA",
+}
+
+// Finds the files with merge conflicts, which are the files with entries in a stage above 0 in the index.
+// go-git does not report them in the worktree status.
+func unmergedFiles(repository *git.Repository) (map[string]string, error) {
+	idx, err := repository.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	stages := make(map[string]int)
+	for _, entry := range idx.Entries {
+		if entry.Stage > 0 {
+			stages[entry.Name] |= 1 << entry.Stage
+		}
+	}
+	unmerged := make(map[string]string, len(stages))
+	for filename, stageBits := range stages {
+		unmerged[filename] = unmergedStatusCodes[stageBits]
+	}
+	return unmerged, nil
+}
+
+func (native nativeGit) ResetFiles(changes gitChanges) error {
+	repository, err := native.open()
+	if err != nil {
+		return err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return &GitError{Operation: "checkout", Err: err}
+	}
+	root := worktree.Filesystem.Root()
+
+	added := make(map[string]bool)
+	if len(changes.added) > 0 {
+		index, err := repository.Storer.Index()
+		if err != nil {
+			return &GitError{Operation: "rm", Err: err}
+		}
+		for _, filename := range changes.added {
+			added[filename] = true
+			index.Remove(filename)
+			if err := os.Remove(filepath.Join(root, filepath.FromSlash(filename))); err != nil {
+				return err
+			}
+		}
+		if err := repository.Storer.SetIndex(index); err != nil {
+			return &GitError{Operation: "rm", Err: err}
+		}
+	}
+
+	head, err := repository.Head()
+	if err != nil {
+		return &GitError{Operation: "checkout", Err: err}
+	}
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		return &GitError{Operation: "checkout", Err: err}
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return &GitError{Operation: "checkout", Err: err}
+	}
+
+	for _, filename := range append(changes.updated, changes.removed...) {
+		if added[filename] {
+			continue
+		}
+		file, err := tree.File(filename)
+		if err != nil {
+			return &GitError{Operation: "checkout", Err: errors.Wrap(err, filename)}
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return &GitError{Operation: "checkout", Err: errors.Wrap(err, filename)}
+		}
+		mode, err := file.Mode.ToOSFileMode()
+		if err != nil {
+			mode = 0644
+		}
+		path := filepath.Join(root, filepath.FromSlash(filename))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), mode); err != nil {
+			return err
+		}
+		if _, err := worktree.Add(filename); err != nil {
+			return &GitError{Operation: "checkout", Err: errors.Wrap(err, filename)}
+		}
+	}
+	return nil
+}
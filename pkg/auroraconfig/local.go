@@ -0,0 +1,82 @@
+package auroraconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+func hasAboutFile(folder string) bool {
+	_, err := os.Stat(filepath.Join(folder, "about.json"))
+	return err == nil
+}
+
+// Returns the folder of the checked out AuroraConfig that contains the path.  Both the AuroraConfig and the env
+// folders have an about.json, so this is the outermost of the nested folders with an about.json.
+func FindLocalRoot(path string) (root string, err error) {
+	root, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for !hasAboutFile(root) {
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", errors.New(path + " is not in a checked out AuroraConfig, no about.json found")
+		}
+		root = parent
+	}
+	for parent := filepath.Dir(root); parent != root && hasAboutFile(parent); parent = filepath.Dir(root) {
+		root = parent
+	}
+	return root, nil
+}
+
+// Reads the JSON files of a checked out AuroraConfig.  The path may be the repository or a folder inside it.
+// Hidden files and folders, and files that are not JSON, are left out.
+func ReadLocalAuroraConfig(path string) (auroraConfig serverapi.AuroraConfig, root string, err error) {
+	root, err = FindLocalRoot(path)
+	if err != nil {
+		return auroraConfig, "", err
+	}
+
+	auroraConfig.Files = make(map[string]json.RawMessage)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		filename := filepath.ToSlash(relative)
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.New("Could not read file " + filename + ": " + err.Error())
+		}
+		var parsed interface{}
+		if err := json.Unmarshal(content, &parsed); err != nil {
+			return errors.New(filename + " is not valid JSON: " + err.Error())
+		}
+		auroraConfig.Files[filename] = content
+		return nil
+	})
+	if err != nil {
+		return auroraConfig, "", err
+	}
+	return auroraConfig, root, nil
+}
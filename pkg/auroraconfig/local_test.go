@@ -0,0 +1,57 @@
+package auroraconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLocalAuroraConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "ao-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"about.json":          `{"schemaVersion": "v1"}`,
+		"api.json":            `{"version": "1"}`,
+		"test/about.json":     `{"cluster": "utv"}`,
+		"test/api.json":       `{"replicas": 2}`,
+		"test/notes.txt":      "not part of the AuroraConfig",
+		".git/config.json":    "{}",
+		"test/.hidden.json":   "{}",
+		"test/.secret/a.json": "{}",
+	}
+	for filename, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(filename))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auroraConfig, foundRoot, err := ReadLocalAuroraConfig(filepath.Join(root, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := filepath.EvalSymlinks(root); foundRoot != root && foundRoot != resolved {
+		t.Errorf("Expected root %v, got %v", root, foundRoot)
+	}
+	if len(auroraConfig.Files) != 4 {
+		t.Errorf("Expected 4 files, got %v", auroraConfig.Files)
+	}
+	if string(auroraConfig.Files["test/api.json"]) != `{"replicas": 2}` {
+		t.Errorf("Unexpected content of test/api.json: %v", string(auroraConfig.Files["test/api.json"]))
+	}
+
+	ioutil.WriteFile(filepath.Join(root, "test", "broken.json"), []byte("{"), 0644)
+	if _, _, err := ReadLocalAuroraConfig(root); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+
+	if _, _, err := ReadLocalAuroraConfig(os.TempDir()); err == nil {
+		t.Error("Expected an error outside a checked out AuroraConfig")
+	}
+}
@@ -1,99 +1,168 @@
 package auroraconfig
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/skatteetaten/ao/pkg/configuration"
 	"github.com/skatteetaten/ao/pkg/openshift"
-	"github.com/skatteetaten/ao/pkg/serverapi"
+	"github.com/spf13/viper"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
 const GIT_URL_FORMAT = "https://%s@git.aurora.skead.no/scm/ac/%s.git"
 
-// TODO: Add debug
-func GitCommand(args ...string) (string, error) {
-	command := exec.Command("git", args...)
-	command.Stderr = os.Stderr
-	cmdReader, err := command.StdoutPipe()
-	if err != nil {
-		return "", err
-	}
+// Returned when a git operation fails, with the error from go-git or the message from the git command
+type GitError struct {
+	Operation string
+	Err       error
+}
 
-	scanner := bufio.NewScanner(cmdReader)
+func (gitError *GitError) Error() string {
+	return "git " + gitError.Operation + " failed: " + gitError.Err.Error()
+}
 
-	err = command.Start()
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to start git command")
-	}
+/*
+The git operations on a checked out AuroraConfig, in the folder given to gitClientFor.  They are done with go-git,
+so git does not have to be installed.  If AO_GIT_COMMAND is set, or go-git is not able to do an operation,
+the git command is used instead.
+
+Status returns the files in the same format as git status --porcelain -z --untracked-files=all.
+*/
+type gitClient interface {
+	Clone(url string, path string) (string, error)
+	Pull() (string, error)
+	Fetch() error
+	RemoteUrl(remote string) (string, error)
+	Root() (string, error)
+	Upstream() (string, error)
+	Diverged(upstream string) (localCommits bool, remoteCommits bool, err error)
+	Status(paths []string) (string, error)
+	ResetFiles(changes gitChanges) error
+}
 
-	message := ""
-	for scanner.Scan() {
-		message = fmt.Sprintf("%s%s\n", message, scanner.Text())
+// Returns the client for the repository in the folder, or the current folder if it is empty
+func gitClientFor(dir string) gitClient {
+	viper.BindEnv("AO_GIT_COMMAND")
+	if viper.GetBool("AO_GIT_COMMAND") {
+		return commandGit{dir: dir}
 	}
+	return nativeGit{dir: dir}
+}
+
+func getGitClient() gitClient {
+	return gitClientFor("")
+}
+
+// Runs the git command, and returns the output.  If the command fails, the error has the message from git.
+func GitCommand(args ...string) (string, error) {
+	return gitCommandIn("", args...)
+}
 
-	err = command.Wait()
+func gitCommandIn(dir string, args ...string) (string, error) {
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to wait for git command")
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", &GitError{Operation: args[0], Err: errors.New(message)}
 	}
-
-	return message, nil
+	return string(output), nil
 }
 
 func Checkout(url string, outputPath string) (string, error) {
-	return GitCommand("clone", url, outputPath)
+	return getGitClient().Clone(url, outputPath)
 }
 
 func Pull() (string, error) {
-	if output, err := GitCommand("pull"); err != nil {
-		return "", errors.New("pull failed")
-	} else {
-		return output, nil
-	}
+	return getGitClient().Pull()
 }
 
-func Save(url string, config *configuration.ConfigurationClass) (string, error) {
+// Saves the changed, new and deleted AuroraConfig files in the working copy to Boober, and updates the working copy
+// with the commit Boober makes.  If paths are given, only the files in those paths are saved.
+// Files ignored by git, hidden files and files that are not JSON are never saved, and untracked files are left alone.
+func Save(url string, paths []string, config *configuration.ConfigurationClass) (string, error) {
 	if err := ValidateRepo(url); err != nil {
 		return "", err
 	}
 
-	fetchOrigin()
-
-	if err := checkForNewCommits(); err != nil {
+	client := getGitClient()
+	if err := client.Fetch(); err != nil {
 		return "", err
 	}
 
-	var statuses []string
-	if status, err := GitCommand("status", "-s"); err != nil {
+	upstream, err := client.Upstream()
+	if err != nil {
 		return "", err
-	} else {
-		statuses = strings.Fields(status)
 	}
-
-	if err := checkRepoForChanges(statuses); err != nil {
+	if err := checkForNewCommits(client, upstream); err != nil {
 		return "", err
 	}
 
-	if err := handleAuroraConfigCommit(statuses, config); err != nil {
+	root, err := client.Root()
+	if err != nil {
 		return "", err
 	}
+	rootPaths, err := pathsInRepository(root, paths)
+	if err != nil {
+		return "", err
+	}
+	client = gitClientFor(root)
 
-	// Delete untracked files
-	if _, err := GitCommand("clean", "-fd"); err != nil {
+	status, err := client.Status(rootPaths)
+	if err != nil {
+		return "", err
+	}
+	changes := parseGitStatus(status)
+	if err := checkRepoForChanges(changes); err != nil {
 		return "", err
 	}
 
-	// Reset branch before pull
-	if _, err := GitCommand("checkout", "."); err != nil {
+	if err := handleAuroraConfigCommit(root, changes, config); err != nil {
 		return "", err
 	}
 
-	return Pull()
+	output := changes.String()
+	if err := client.ResetFiles(changes); err != nil {
+		return output, err
+	}
+	pullOutput, err := client.Pull()
+	if err != nil {
+		return output, errors.New("The files are saved, but updating the working copy failed, please use ao pull: " + err.Error())
+	}
+	return output + pullOutput, nil
+}
+
+// Makes the paths given on the command line relative to the root of the repository
+func pathsInRepository(root string, paths []string) (rootPaths []string, err error) {
+	if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolvedRoot
+	}
+	for _, path := range paths {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if resolved, err := filepath.EvalSymlinks(absolute); err == nil {
+			absolute = resolved
+		}
+		relative, err := filepath.Rel(root, absolute)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, "../") {
+			return nil, errors.New(path + " is not in the AuroraConfig repository")
+		}
+		rootPaths = append(rootPaths, filepath.ToSlash(relative))
+	}
+	return rootPaths, nil
 }
 
 func UpdateLocalRepository(affiliation string, config *openshift.OpenshiftConfig) error {
@@ -102,34 +171,16 @@ func UpdateLocalRepository(affiliation string, config *openshift.OpenshiftConfig
 		return errors.New("No local repository for affiliation " + affiliation)
 	}
 
-	wd, _ := os.Getwd()
-	if err := os.Chdir(path); err != nil {
-		return err
-	}
-
-	if _, err := Pull(); err != nil {
-		return err
-	}
-
-	return os.Chdir(wd)
+	_, err := gitClientFor(path).Pull()
+	return err
 }
 
 func ValidateRepo(expectedUrl string) error {
-	output, err := GitCommand("remote", "-v")
+	repoUrl, err := getGitClient().RemoteUrl("origin")
 	if err != nil {
 		return err
 	}
 
-	remotes := strings.Fields(output)
-
-	var repoUrl string
-	for i, v := range remotes {
-		if v == "origin" && len(remotes) > i+1 {
-			repoUrl = remotes[i+1]
-			break
-		}
-	}
-
 	if repoUrl != expectedUrl {
 		message := fmt.Sprintf(`Wrong repository.
 Expected remote to be %s, actual %s.`, expectedUrl, repoUrl)
@@ -139,18 +190,91 @@ Expected remote to be %s, actual %s.`, expectedUrl, repoUrl)
 	return nil
 }
 
-func handleAuroraConfigCommit(statuses []string, config *configuration.ConfigurationClass) error {
+// The files to save, with paths relative to the root of the repository
+type gitChanges struct {
+	updated []string
+	added   []string
+	removed []string
+}
+
+func (changes gitChanges) String() (output string) {
+	for _, filename := range changes.updated {
+		output += "Uploaded " + filename + "\n"
+	}
+	for _, filename := range changes.removed {
+		output += "Removed " + filename + "\n"
+	}
+	return output
+}
+
+// Only JSON files outside hidden folders are part of the AuroraConfig
+func IsAuroraConfigFile(filename string) bool {
+	if !strings.HasSuffix(filename, ".json") {
+		return false
+	}
+	for _, part := range strings.Split(filename, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses the output of git status --porcelain -z.  Each entry is XY and the path, and renames and copies are
+// followed by an entry with the original path.
+func parseGitStatus(status string) (changes gitChanges) {
+	entries := strings.Split(status, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, filename := entry[0], entry[1], entry[3:]
+
+		if x == 'R' || x == 'C' {
+			i++
+			if x == 'R' && i < len(entries) && IsAuroraConfigFile(entries[i]) {
+				changes.removed = append(changes.removed, entries[i])
+			}
+		}
+		if !IsAuroraConfigFile(filename) {
+			continue
+		}
+
+		switch {
+		case x == 'A' && y == 'D':
+			// Added and deleted again, there is nothing to save
+		case x == 'D' || y == 'D':
+			changes.removed = append(changes.removed, filename)
+		case x == '?' || x == 'A' || x == 'R' || x == 'C':
+			changes.updated = append(changes.updated, filename)
+			changes.added = append(changes.added, filename)
+		default:
+			changes.updated = append(changes.updated, filename)
+		}
+	}
+	sort.Strings(changes.updated)
+	sort.Strings(changes.removed)
+	return changes
+}
+
+func handleAuroraConfigCommit(root string, changes gitChanges, config *configuration.ConfigurationClass) error {
 	ac, err := GetAuroraConfig(config)
 
 	if err != nil {
 		return errors.Wrap(err, "Failed getting AuroraConfig")
 	}
 
-	if err = addFilesToAuroraConfig(&ac); err != nil {
-		return errors.Wrap(err, "Failed adding files to AuroraConfig")
+	for _, filename := range changes.updated {
+		file, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(filename)))
+		if err != nil {
+			return errors.Wrap(err, "Could not read file "+filename)
+		}
+		ac.Files[filename] = file
+	}
+	for _, filename := range changes.removed {
+		delete(ac.Files, filename)
 	}
-
-	removeFilesFromAuroraConfig(statuses, &ac)
 
 	if err = PutAuroraConfig(ac, config); err != nil {
 		return errors.Wrap(err, "Failed committing AuroraConfig")
@@ -159,74 +283,128 @@ func handleAuroraConfigCommit(statuses []string, config *configuration.Configura
 	return nil
 }
 
-func checkRepoForChanges(statuses []string) error {
-	if len(statuses) == 0 {
+func checkRepoForChanges(changes gitChanges) error {
+	if len(changes.updated) == 0 && len(changes.removed) == 0 {
 		return errors.New("Nothing to save")
 	}
 
 	return nil
 }
 
-func fetchOrigin() (string, error) {
-
-	return GitCommand("fetch", "origin")
-}
-
-func checkForNewCommits() error {
+func checkForNewCommits(client gitClient, upstream string) error {
+	localCommits, remoteCommits, err := client.Diverged(upstream)
+	if err != nil {
+		return err
+	}
 
-	if err := compareGitLog("origin/master..HEAD"); err != nil {
+	if localCommits {
 		return errors.New(`You have committed local changes.
 Please revert them with: git reset HEAD^`)
 	}
 
-	if err := compareGitLog("HEAD..origin/master"); err != nil {
+	if remoteCommits {
 		return errors.New(`Please update to latest configuration with: ao pull`)
 	}
 
 	return nil
 }
 
-func compareGitLog(compare string) error {
-	output, err := GitCommand("log", compare, "--oneline")
-	if err != nil {
-		return err
-	}
-
-	if len(output) > 0 {
-		return errors.New("new commits")
-	}
+// The git operations done with the git command
+type commandGit struct {
+	dir string
+}
 
-	return nil
+func (git commandGit) Clone(url string, path string) (string, error) {
+	return gitCommandIn(git.dir, "clone", url, path)
 }
 
-func addFilesToAuroraConfig(ac *serverapi.AuroraConfig) error {
-	wd, _ := os.Getwd()
+func (git commandGit) Pull() (string, error) {
+	return gitCommandIn(git.dir, "pull", "--ff-only")
+}
 
-	return filepath.Walk(wd, func(path string, info os.FileInfo, err error) error {
+func (git commandGit) Fetch() error {
+	_, err := gitCommandIn(git.dir, "fetch", "origin")
+	return err
+}
 
-		filename := strings.TrimPrefix(path, wd+"/")
+func (git commandGit) RemoteUrl(remote string) (string, error) {
+	output, err := gitCommandIn(git.dir, "remote", "-v")
+	if err != nil {
+		return "", err
+	}
 
-		if strings.Contains(filename, ".git") || strings.Contains(filename, ".secret") || info.IsDir() {
-			return nil
+	remotes := strings.Fields(output)
+	for i, v := range remotes {
+		if v == remote && len(remotes) > i+1 {
+			return remotes[i+1], nil
 		}
+	}
+	return "", nil
+}
 
-		file, err := ioutil.ReadFile(wd + "/" + filename)
+func (git commandGit) Root() (string, error) {
+	output, err := gitCommandIn(git.dir, "rev-parse", "--show-toplevel")
+	return strings.TrimSpace(output), err
+}
 
-		if err != nil {
-			return errors.Wrap(err, "Could not read file "+filename)
-		}
+func (git commandGit) Upstream() (string, error) {
+	branch, err := gitCommandIn(git.dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	branch = strings.TrimSpace(branch)
+	if branch == "HEAD" {
+		return "", errors.New("Not on a branch, please check out a branch before saving")
+	}
 
-		ac.Files[filename] = file
+	upstream, err := gitCommandIn(git.dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil || strings.TrimSpace(upstream) == "" {
+		return "origin/" + branch, nil
+	}
+	return strings.TrimSpace(upstream), nil
+}
 
-		return nil
-	})
+func (git commandGit) Diverged(upstream string) (localCommits bool, remoteCommits bool, err error) {
+	local, err := gitCommandIn(git.dir, "log", upstream+"..HEAD", "--oneline")
+	if err != nil {
+		return false, false, err
+	}
+	remote, err := gitCommandIn(git.dir, "log", "HEAD.."+upstream, "--oneline")
+	if err != nil {
+		return false, false, err
+	}
+	return len(local) > 0, len(remote) > 0, nil
+}
+
+func (git commandGit) Status(paths []string) (string, error) {
+	return gitCommandIn(git.dir, append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, paths...)...)
 }
 
-func removeFilesFromAuroraConfig(statuses []string, ac *serverapi.AuroraConfig) error {
-	for i, v := range statuses {
-		if v == "D" && len(statuses) > i+1 {
-			delete(ac.Files, statuses[i+1])
+func (git commandGit) ResetFiles(changes gitChanges) error {
+	added := make(map[string]bool)
+	for _, filename := range changes.added {
+		added[filename] = true
+	}
+	if len(changes.added) > 0 {
+		if _, err := gitCommandIn(git.dir, append([]string{"rm", "-q", "--cached", "--ignore-unmatch", "--"}, changes.added...)...); err != nil {
+			return err
+		}
+		for _, filename := range changes.added {
+			if err := os.Remove(filepath.Join(git.dir, filepath.FromSlash(filename))); err != nil {
+				return err
+			}
 		}
 	}
-	return nil
+
+	var tracked []string
+	for _, filename := range append(changes.updated, changes.removed...) {
+		if !added[filename] {
+			tracked = append(tracked, filename)
+		}
+	}
+	if len(tracked) == 0 {
+		return nil
+	}
+	_, err := gitCommandIn(git.dir, append([]string{"checkout", "HEAD", "--"}, tracked...)...)
+	return err
 }
@@ -5,6 +5,20 @@ import (
 	"fmt"
 	"os/exec"
 	"os"
+	"reflect"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/cmdoptions"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/openshift"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func TestValidateRepo(t *testing.T) {
@@ -21,3 +35,142 @@ func TestValidateRepo(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestParseGitStatus(t *testing.T) {
+	status := " M test/api.json\x00?? test/new.json\x00 D old.json\x00R  prod/api.json\x00utv/api.json\x00" +
+		"?? test/.api.json.swp\x00?? notes.txt\x00A  added.json\x00?? .idea/workspace.json\x00"
+
+	changes := parseGitStatus(status)
+
+	expectedUpdated := []string{"added.json", "prod/api.json", "test/api.json", "test/new.json"}
+	expectedRemoved := []string{"old.json", "utv/api.json"}
+	if !reflect.DeepEqual(changes.updated, expectedUpdated) {
+		t.Errorf("Expected updated %v, got %v", expectedUpdated, changes.updated)
+	}
+	if !reflect.DeepEqual(changes.removed, expectedRemoved) {
+		t.Errorf("Expected removed %v, got %v", expectedRemoved, changes.removed)
+	}
+	if len(changes.added) != 3 {
+		t.Errorf("Expected 3 added files, got %v", changes.added)
+	}
+}
+
+// Commits the files in the working copy of the repository, and pushes the commit if the repository has a remote
+func commitFiles(t *testing.T, repository *git.Repository, files map[string]string) {
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(worktree.Filesystem.Root(), filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "boober", Email: "boober@test", When: time.Now()}
+	if _, err := worktree.Commit("Saved by ao", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.Remote("origin"); err == nil {
+		if err := repository.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSaveWithUnrelatedChange(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is needed for a local remote")
+	}
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	defer os.Setenv("AO_GIT_COMMAND", os.Getenv("AO_GIT_COMMAND"))
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "ao-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("HOME", dir)
+	os.Unsetenv("AO_GIT_COMMAND")
+
+	// Boober's working copy, which commits and pushes what is saved
+	booberRepository, err := git.PlainInit(filepath.Join(dir, "boober"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"about.json": `{"cluster": "utv"}`, "api.json": `{"version": "1"}`}
+	commitFiles(t, booberRepository, files)
+	remote := filepath.Join(dir, "remote.git")
+	if _, err := git.PlainClone(remote, true, &git.CloneOptions{URL: filepath.Join(dir, "boober")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "-C", filepath.Join(dir, "boober"), "remote", "add", "origin", remote).Run(); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(dir, "local")
+	if _, err := git.PlainClone(local, false, &git.CloneOptions{URL: remote}); err != nil {
+		t.Fatal(err)
+	}
+
+	boober := "http://boober.test"
+	auroraConfig := serverapi.AuroraConfig{Files: map[string]json.RawMessage{}, Versions: map[string]string{}}
+	for filename, content := range files {
+		auroraConfig.Files[filename] = json.RawMessage(content)
+	}
+	gock.New(boober).
+		Get("/affiliation/paas/auroraconfig").
+		Reply(200).
+		JSON(map[string]interface{}{"success": true, "items": []interface{}{auroraConfig}})
+	var saved bool
+	gock.New(boober).
+		Put("/affiliation/paas/auroraconfig").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			if saved {
+				return true, nil
+			}
+			saved = true
+			var received serverapi.AuroraConfig
+			if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+				return false, err
+			}
+			commitFiles(t, booberRepository, map[string]string{"api.json": string(received.Files["api.json"])})
+			return true, nil
+		}).
+		Reply(200).
+		JSON(map[string]interface{}{"success": true, "items": []interface{}{}})
+
+	os.Chdir(local)
+	ioutil.WriteFile(filepath.Join(local, "api.json"), []byte(`{"version":"2"}`), 0644)
+	ioutil.WriteFile(filepath.Join(local, "about.json"), []byte(`{"cluster": "test"}`), 0644)
+
+	config := &configuration.ConfigurationClass{
+		PersistentOptions: &cmdoptions.CommonCommandOptions{},
+		OpenshiftConfig: &openshift.OpenshiftConfig{
+			APICluster:  "utv",
+			Affiliation: "paas",
+			Clusters:    []*openshift.OpenshiftCluster{{Name: "utv", BooberUrl: boober, Reachable: true}},
+		},
+	}
+	if output, err := Save(remote, []string{"api.json"}, config); err != nil {
+		t.Fatalf("Save failed: %v\n%v", err, output)
+	}
+
+	status, err := getGitClient().Status(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != " M about.json\x00" {
+		t.Errorf("Expected only the unsaved change to about.json, got %q", status)
+	}
+	if content, _ := ioutil.ReadFile(filepath.Join(local, "api.json")); string(content) != `{"version":"2"}` {
+		t.Errorf("The saved file was not updated from the commit, got %v", string(content))
+	}
+	if localCommits, remoteCommits, err := getGitClient().Diverged("origin/master"); err != nil || localCommits || remoteCommits {
+		t.Errorf("The working copy is not at the saved commit: %v %v %v", localCommits, remoteCommits, err)
+	}
+}
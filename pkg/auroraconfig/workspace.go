@@ -0,0 +1,147 @@
+package auroraconfig
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The state of the checked out AuroraConfig for an affiliation
+type WorkspaceStatus struct {
+	Affiliation string
+	Path        string
+	Dirty       bool
+	Conflicted  bool
+	Ahead       bool
+	Behind      bool
+	Err         error
+}
+
+func (status WorkspaceStatus) State() string {
+	if status.Err != nil {
+		return "error"
+	}
+	var states []string
+	if status.Conflicted {
+		states = append(states, "conflicted")
+	}
+	if status.Dirty {
+		states = append(states, "dirty")
+	}
+	if status.Ahead {
+		states = append(states, "ahead")
+	}
+	if status.Behind {
+		states = append(states, "behind")
+	}
+	if len(states) == 0 {
+		return "clean"
+	}
+	return strings.Join(states, ", ")
+}
+
+// A checkout can be pulled if the pull is a fast forward that does not touch local changes
+func (status WorkspaceStatus) CanPull() bool {
+	return status.Err == nil && !status.Dirty && !status.Conflicted && !status.Ahead
+}
+
+// Finds the changes to tracked files, and the files with merge conflicts, in the output of git status --porcelain -z.
+// Untracked files do not stop a pull, so they are not counted.
+func classifyStatus(status string) (dirty bool, conflicted bool) {
+	entries := strings.Split(status, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y := entry[0], entry[1]
+		if x == 'R' || x == 'C' {
+			i++
+		}
+		switch {
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			conflicted = true
+		case x == '?' || x == '!':
+		default:
+			dirty = true
+		}
+	}
+	return dirty, conflicted
+}
+
+func getCheckoutStatus(affiliation string, path string, fetch bool) (status WorkspaceStatus) {
+	status = WorkspaceStatus{Affiliation: affiliation, Path: path}
+	if _, err := os.Stat(path); err != nil {
+		status.Err = errors.New("The checkout path does not exist")
+		return status
+	}
+
+	client := gitClientFor(path)
+	if fetch {
+		if err := client.Fetch(); err != nil {
+			status.Err = err
+			return status
+		}
+	}
+
+	gitStatus, err := client.Status(nil)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.Dirty, status.Conflicted = classifyStatus(gitStatus)
+
+	upstream, err := client.Upstream()
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.Ahead, status.Behind, status.Err = client.Diverged(upstream)
+	return status
+}
+
+// Returns the state of each checked out AuroraConfig, sorted by affiliation.  With fetch, the remote branches are
+// updated first, so that behind is current.
+func GetWorkspaceStatus(checkoutPaths map[string]string, fetch bool) (statuses []WorkspaceStatus) {
+	var affiliations []string
+	for affiliation := range checkoutPaths {
+		affiliations = append(affiliations, affiliation)
+	}
+	sort.Strings(affiliations)
+
+	for _, affiliation := range affiliations {
+		statuses = append(statuses, getCheckoutStatus(affiliation, checkoutPaths[affiliation], fetch))
+	}
+	return statuses
+}
+
+// Pulls the checked out AuroraConfigs that are behind and can be pulled.  The others are skipped and reported.
+func PullWorkspace(checkoutPaths map[string]string) (output string, err error) {
+	var failed []string
+	for _, status := range GetWorkspaceStatus(checkoutPaths, true) {
+		switch {
+		case status.Err != nil:
+			output += status.Affiliation + ": skipped, " + status.Err.Error() + "\n"
+			failed = append(failed, status.Affiliation)
+		case !status.CanPull():
+			output += status.Affiliation + ": skipped, " + status.State() + "\n"
+			failed = append(failed, status.Affiliation)
+		case !status.Behind:
+			output += status.Affiliation + ": up to date\n"
+		default:
+			pullOutput, pullErr := gitClientFor(status.Path).Pull()
+			if pullErr != nil {
+				output += status.Affiliation + ": " + pullErr.Error() + "\n"
+				failed = append(failed, status.Affiliation)
+				continue
+			}
+			output += status.Affiliation + ": " + strings.TrimSpace(pullOutput) + "\n"
+		}
+	}
+	if len(failed) > 0 {
+		return output, errors.New("Not pulled: " + strings.Join(failed, ", "))
+	}
+	return output, nil
+}
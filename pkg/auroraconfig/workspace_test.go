@@ -0,0 +1,27 @@
+package auroraconfig
+
+import (
+	"testing"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	testCases := []struct {
+		status     string
+		dirty      bool
+		conflicted bool
+	}{
+		{"", false, false},
+		{"?? new.json\x00", false, false},
+		{" M test/api.json\x00", true, false},
+		{"R  prod/api.json\x00utv/api.json\x00", true, false},
+		{"UU test/api.json\x00?? new.json\x00", false, true},
+		{"AA about.json\x00 D old.json\x00", true, true},
+	}
+
+	for i, testCase := range testCases {
+		dirty, conflicted := classifyStatus(testCase.status)
+		if dirty != testCase.dirty || conflicted != testCase.conflicted {
+			t.Errorf("Case %d: got dirty %v and conflicted %v", i, dirty, conflicted)
+		}
+	}
+}
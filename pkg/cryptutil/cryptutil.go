@@ -0,0 +1,139 @@
+package cryptutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/spf13/viper"
+)
+
+/*
+Secret files may be encrypted with age (https://age-encryption.org), either to an X25519 recipient or with a passphrase,
+in binary or ASCII armored form.  Only files with a .age suffix are decrypted when they are read, and the suffix is
+removed from the secret name.  Other files are read as they are, even if they look like age files.
+
+The keys are read from a local key file, by default ~/.ao-keys.txt.  Each line is either an age identity
+(AGE-SECRET-KEY-1...) or a passphrase.  Empty lines and lines starting with # are ignored.
+The key file must not be readable by others.
+*/
+
+const defaultKeyFilename = "/.ao-keys.txt"
+const encryptedSuffix = ".age"
+
+const ageHeader = "age-encryption.org/"
+
+var keyFile string
+var identities []age.Identity
+
+// Sets the key file to read the keys from.  An empty filename selects the default key file.
+func SetKeyFile(filename string) {
+	if filename != keyFile {
+		identities = nil
+	}
+	keyFile = filename
+}
+
+func GetKeyFile() string {
+	if keyFile != "" {
+		return keyFile
+	}
+	viper.BindEnv("HOME")
+	return viper.GetString("HOME") + defaultKeyFilename
+}
+
+func loadIdentities() (err error) {
+	if identities != nil {
+		return nil
+	}
+	filename := GetKeyFile()
+	info, err := os.Stat(filename)
+	if err != nil {
+		return errors.New("Unable to read key file " + filename + ": " + err.Error())
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return errors.New("Key file " + filename + " is accessible by others, please chmod 600 " + filename)
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.New("Unable to read key file " + filename + ": " + err.Error())
+	}
+	identities, err = parseKeys(content)
+	if err != nil {
+		return errors.New("Error in key file " + filename + ": " + err.Error())
+	}
+	return nil
+}
+
+func parseKeys(content []byte) (keys []age.Identity, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "AGE-SECRET-KEY-") {
+			identity, err := age.ParseX25519Identity(line)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, identity)
+		} else {
+			identity, err := age.NewScryptIdentity(line)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, identity)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("No keys found")
+	}
+	return keys, nil
+}
+
+// Decrypts an age encrypted file with the keys in the key file
+func Decrypt(content []byte) (plaintext []byte, err error) {
+	if err := loadIdentities(); err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = bytes.NewReader(content)
+	if !bytes.HasPrefix(content, []byte(ageHeader)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimLeft(content, " \t\r\n")))
+	}
+	reader, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// Reads a secret file, and decrypts it if the name has the .age suffix.  The secret name is the file name
+// without the suffix.
+func ReadSecretFile(filename string) (content []byte, secretName string, err error) {
+	content, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	secretName = filepath.Base(filename)
+	if !strings.HasSuffix(secretName, encryptedSuffix) {
+		return content, secretName, nil
+	}
+
+	content, err = Decrypt(content)
+	if err != nil {
+		return nil, "", errors.New("Unable to decrypt " + filename + ": " + err.Error())
+	}
+	return content, strings.TrimSuffix(secretName, encryptedSuffix), nil
+}
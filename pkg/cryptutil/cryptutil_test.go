@@ -0,0 +1,102 @@
+package cryptutil
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func encrypt(t *testing.T, plaintext string, armored bool, recipient age.Recipient) []byte {
+	var buffer bytes.Buffer
+	var dst io.Writer = &buffer
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(&buffer)
+		dst = armorWriter
+	}
+	writer, err := age.Encrypt(dst, recipient)
+	if err != nil {
+		t.Fatalf("Error in Encrypt: %v", err.Error())
+	}
+	writer.Write([]byte(plaintext))
+	writer.Close()
+	if armorWriter != nil {
+		armorWriter.Close()
+	}
+	return buffer.Bytes()
+}
+
+func TestReadSecretFile(t *testing.T) {
+	folder, err := ioutil.TempDir("", "ao_cryptutil_")
+	if err != nil {
+		t.Fatalf("Error creating temporary folder: %v", err.Error())
+	}
+	defer os.RemoveAll(folder)
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Error generating identity: %v", err.Error())
+	}
+	passphraseRecipient, err := age.NewScryptRecipient("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Error creating passphrase recipient: %v", err.Error())
+	}
+	passphraseRecipient.SetWorkFactor(10)
+
+	keyFile := filepath.Join(folder, "keys.txt")
+	keys := "# test keys\n" + identity.String() + "\ncorrect horse battery staple\n"
+	ioutil.WriteFile(keyFile, []byte(keys), 0600)
+	SetKeyFile(keyFile)
+	defer SetKeyFile("")
+
+	files := map[string][]byte{
+		"plain.properties":       []byte("user=plain\n"),
+		"x25519.properties.age":  encrypt(t, "user=x25519\n", false, identity.Recipient()),
+		"armored.properties.age": encrypt(t, "user=armored\n", true, identity.Recipient()),
+		"header.properties":      []byte(ageHeader + "v1 is not encrypted\n"),
+		"passphrase.age":         encrypt(t, "user=passphrase\n", false, passphraseRecipient),
+	}
+	expected := map[string]string{
+		"plain.properties":       "plain.properties",
+		"x25519.properties.age":  "x25519.properties",
+		"armored.properties.age": "armored.properties",
+		"header.properties":      "header.properties",
+		"passphrase.age":         "passphrase",
+	}
+	contents := map[string]string{
+		"plain.properties":       "user=plain\n",
+		"x25519.properties.age":  "user=x25519\n",
+		"armored.properties.age": "user=armored\n",
+		"header.properties":      ageHeader + "v1 is not encrypted\n",
+		"passphrase.age":         "user=passphrase\n",
+	}
+
+	for filename, content := range files {
+		path := filepath.Join(folder, filename)
+		ioutil.WriteFile(path, content, 0600)
+		plaintext, secretName, err := ReadSecretFile(path)
+		if err != nil {
+			t.Errorf("Error in ReadSecretFile for %v: %v", filename, err.Error())
+			continue
+		}
+		if secretName != expected[filename] {
+			t.Errorf("ReadSecretFile returned unexpected name for %v, expected %v, got %v", filename, expected[filename], secretName)
+		}
+		if string(plaintext) != contents[filename] {
+			t.Errorf("ReadSecretFile returned unexpected content for %v, expected %q, got %q", filename, contents[filename], plaintext)
+		}
+	}
+
+	os.Chmod(keyFile, 0644)
+	SetKeyFile("")
+	SetKeyFile(keyFile)
+	if _, _, err := ReadSecretFile(filepath.Join(folder, "x25519.properties.age")); err == nil {
+		t.Errorf("ReadSecretFile accepted a key file readable by others")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/skatteetaten/ao/pkg/fuzzyargs"
 	"github.com/skatteetaten/ao/pkg/jsonutil"
 	"github.com/skatteetaten/ao/pkg/serverapi"
+	"github.com/skatteetaten/ao/pkg/vault"
 )
 
 // TODO: Fyll inn envs ved deploy av en app
@@ -21,30 +22,33 @@ import (
 
 const UsageString = "Usage: deploy <env> <app> <env/app> [--all] [--force] [-e env] [-a app] "
 
-type DeployCommand struct {
-	Affiliation string                      `json:"affiliation"`
-	SetupParams jsonutil.SetupParamsPayload `json:"setupParams"`
-}
-
 type DeployClass struct {
-	Configuration *configuration.ConfigurationClass
-	setupCommand  DeployCommand
-	fuzzyArgs     fuzzyargs.FuzzyArgs
-	overrideJsons []string
-	auroraConfig  *serverapi.AuroraConfig
+	Configuration  *configuration.ConfigurationClass
+	OverrideFiles  []string
+	ShowEffective  bool
+	Excludes       []string
+	CheckVaults    bool
+	FromLocal      string
+	setupCommand   jsonutil.SetupCommand
+	fuzzyArgs      fuzzyargs.FuzzyArgs
+	overrideJsons  []string
+	overrides      map[string]json.RawMessage
+	effectiveFiles map[string]json.RawMessage
+	auroraConfig   *serverapi.AuroraConfig
+	exitCode       int
+	report         []ReportEntry
 }
 
-func (deploy *DeployClass) generateJson(
-	affiliation string, dryRun bool) (jsonStr string, err error) {
+var errCancelled = errors.New("Operation cancelled by user")
 
-	applist := deploy.fuzzyArgs.GetApps()
+func (deploy *DeployClass) generateJson(
+	affiliation string, envlist []string, applist []string, dryRun bool) (jsonStr string, err error) {
 
 	if len(applist) != 0 {
 		deploy.setupCommand.SetupParams.Apps = applist
 	} else {
 		deploy.setupCommand.SetupParams.Apps = make([]string, 0)
 	}
-	envlist := deploy.fuzzyArgs.GetEnvs()
 	if len(envlist) != 0 {
 		deploy.setupCommand.SetupParams.Envs = envlist
 	} else {
@@ -52,11 +56,15 @@ func (deploy *DeployClass) generateJson(
 	}
 
 	//setupCommand.SetupParams.DryRun = dryRun
-	deploy.setupCommand.SetupParams.Overrides, err = jsonutil.OverrideJsons2map(deploy.overrideJsons)
-	if err != nil {
-		return "", err
+	deploy.setupCommand.SetupParams.Overrides = deploy.overrides
+	if deploy.setupCommand.SetupParams.Overrides == nil {
+		deploy.setupCommand.SetupParams.Overrides = make(map[string]json.RawMessage)
 	}
 	deploy.setupCommand.Affiliation = affiliation
+	if deploy.FromLocal != "" {
+		// Boober uses the files in the request for this deploy only, instead of the stored AuroraConfig
+		deploy.setupCommand.AuroraConfig = &jsonutil.AuroraConfigPayload{Files: deploy.auroraConfig.Files}
+	}
 
 	var jsonByte []byte
 
@@ -70,79 +78,205 @@ func (deploy *DeployClass) generateJson(
 
 }
 
+type deployGroup struct {
+	envs []string
+	apps []string
+}
+
+// Boober deploys every app in the apps list to every env in the envs list.  When the user has selected
+// specific env/app deployments, the envs that should get the same set of apps are grouped into one request.
+func (deploy *DeployClass) getDeployGroups() (groups []deployGroup) {
+	deployments := deploy.fuzzyArgs.GetDeployments()
+	if deployments == nil {
+		return []deployGroup{{envs: deploy.fuzzyArgs.GetEnvs(), apps: deploy.fuzzyArgs.GetApps()}}
+	}
+
+	var envs []string
+	appsInEnv := make(map[string][]string)
+	for _, deployment := range deployments {
+		parts := strings.Split(deployment, "/")
+		if _, found := appsInEnv[parts[0]]; !found {
+			envs = append(envs, parts[0])
+		}
+		appsInEnv[parts[0]] = append(appsInEnv[parts[0]], parts[1])
+	}
+
+	groupIndex := make(map[string]int)
+	for _, env := range envs {
+		key := strings.Join(appsInEnv[env], ",")
+		if index, found := groupIndex[key]; found {
+			groups[index].envs = append(groups[index].envs, env)
+		} else {
+			groupIndex[key] = len(groups)
+			groups = append(groups, deployGroup{envs: []string{env}, apps: appsInEnv[env]})
+		}
+	}
+	return groups
+}
+
 func (deploy *DeployClass) ExecuteDeploy(args []string, overrideJsons []string, applist []string, envList []string,
 	persistentOptions *cmdoptions.CommonCommandOptions, localDryRun bool, deployAll bool, force bool, deployVersion string, affiliation string) (output string, err error) {
 
+	deploy.exitCode = ExitSuccess
+	deploy.report = nil
+
 	if affiliation != "" {
 		deploy.Configuration.OpenshiftConfig.Affiliation = affiliation
 	}
-	ac, err := auroraconfig.GetAuroraConfig(deploy.Configuration)
+	if deploy.FromLocal != "" {
+		err = deploy.loadLocalAuroraConfig()
+		if err != nil {
+			return "", deploy.setFailure(ExitValidationFailure, err)
+		}
+	} else {
+		ac, err := auroraconfig.GetAuroraConfig(deploy.Configuration)
+		if err != nil {
+			return "", deploy.setFailure(ExitDeployFailure, err)
+		}
+		deploy.auroraConfig = &ac
+	}
+
+	deploy.overrideJsons = overrideJsons
+	err = deploy.prepareOverrides()
 	if err != nil {
-		return "", err
+		return "", deploy.setFailure(ExitValidationFailure, err)
+	}
+	if deploy.ShowEffective {
+		fmt.Print(deploy.getEffectiveFilesString())
 	}
-	deploy.auroraConfig = &ac
 
 	err = deploy.validateDeploy(args, applist, envList, deployAll, force)
+	if err == errCancelled {
+		return "", deploy.setFailure(ExitDeployFailure, err)
+	}
 	if err != nil {
-		return "", err
+		return "", deploy.setFailure(ExitValidationFailure, err)
 	}
 
-	deploy.overrideJsons = overrideJsons
+	if deploy.CheckVaults {
+		err = deploy.checkVaults()
+		if err != nil {
+			return "", deploy.setFailure(ExitValidationFailure, err)
+		}
+	}
 
 	if deployVersion != "" {
 		err = deploy.updateVersion(deployVersion)
 		if err != nil {
-			return "", err
+			return "", deploy.setFailure(ExitValidationFailure, err)
 		}
 	}
 
 	affiliation = deploy.Configuration.GetAffiliation()
-	jsonStr, err := deploy.generateJson(affiliation, persistentOptions.DryRun)
-	if err != nil {
-		return "", err
-	}
 	var apiEndpoint string = "/affiliation/" + affiliation + "/deploy"
-	var responses map[string]string
-	var applicationResults []serverapi.ApplicationResult
 
-	if localDryRun {
-		return fmt.Sprintf("%v", string(jsonutil.PrettyPrintJson(jsonStr))), nil
-	} else {
-		responses, err = serverapi.CallApi(http.MethodPut, apiEndpoint, jsonStr, persistentOptions.ShowConfig,
-			persistentOptions.ShowObjects, false, persistentOptions.Localhost,
-			persistentOptions.Verbose, deploy.Configuration.OpenshiftConfig, persistentOptions.DryRun, persistentOptions.Debug, persistentOptions.ServerApi, persistentOptions.Token)
+	for _, group := range deploy.getDeployGroups() {
+		jsonStr, err := deploy.generateJson(affiliation, group.envs, group.apps, persistentOptions.DryRun)
 		if err != nil {
-			for server := range responses {
-				response, err := serverapi.ParseResponse(responses[server])
-				if err != nil {
-					return "", err
-				}
-				if !response.Success {
-					output, err = serverapi.ResponsItems2MessageString(response)
-				}
-			}
-			return output, nil
+			return "", deploy.setFailure(ExitDeployFailure, err)
 		}
-		for server := range responses {
-			response, err := serverapi.ParseResponse(responses[server])
-			if err != nil {
-				return "", err
+
+		if localDryRun {
+			output += fmt.Sprintf("%v", string(jsonutil.PrettyPrintJson(jsonStr)))
+			continue
+		}
+
+		out, err := deploy.callDeploy(apiEndpoint, jsonStr, persistentOptions)
+		output += out
+		if err != nil {
+			return output, deploy.setFailure(ExitDeployFailure, err)
+		}
+	}
+
+	deploy.exitCode = deploy.resultExitCode()
+	if deploy.exitCode != ExitSuccess {
+		return output, errors.New(exitMessages[deploy.exitCode])
+	}
+	return output, nil
+}
+
+// Checks that the vaults and keys referenced by the deployments exist, with the overrides applied
+func (deploy *DeployClass) checkVaults() error {
+	ac := serverapi.AuroraConfig{Files: make(map[string]json.RawMessage)}
+	for filename, content := range deploy.auroraConfig.Files {
+		ac.Files[filename] = content
+	}
+	for filename, content := range deploy.effectiveFiles {
+		ac.Files[filename] = content
+	}
+
+	var deployments []string
+	for _, group := range deploy.getDeployGroups() {
+		for _, env := range group.envs {
+			for _, app := range group.apps {
+				deployments = append(deployments, env+"/"+app)
 			}
-			if response.Success {
-				applicationResults, err = serverapi.ResponseItems2ApplicationResults(response)
+		}
+	}
+	return vault.CheckDeployments(ac, deployments, deploy.Configuration)
+}
+
+// Sets the exit code for an error that stopped the deploy.  A rejected token is always reported as an auth failure.
+func (deploy *DeployClass) setFailure(exitCode int, err error) error {
+	if serverapi.IsUnauthorized(err.Error()) {
+		exitCode = ExitAuthFailure
+	}
+	deploy.exitCode = exitCode
+	return err
+}
+
+// Sends the deploy to all reachable clusters.  Failures reported by the clusters are recorded in the report and the
+// journal and do not return an error, so that the results from the other clusters are kept.
+func (deploy *DeployClass) callDeploy(apiEndpoint string, jsonStr string, persistentOptions *cmdoptions.CommonCommandOptions) (output string, err error) {
+	responses, err := serverapi.CallApi(http.MethodPut, apiEndpoint, jsonStr, persistentOptions.ShowConfig,
+		persistentOptions.ShowObjects, false, persistentOptions.Localhost,
+		persistentOptions.Verbose, deploy.Configuration.OpenshiftConfig, persistentOptions.DryRun, persistentOptions.Debug, persistentOptions.ServerApi, persistentOptions.Token)
+	if err != nil && len(responses) == 0 {
+		return "", err
+	}
+
+	var journal []JournalEntry
+	defer func() {
+		writeJournal(journal)
+	}()
+
+	for server := range responses {
+		response, err := serverapi.ParseResponse(responses[server])
+		if err != nil {
+			return output, err
+		}
+		if !response.Success {
+			out, _ := serverapi.ResponsItems2MessageString(response)
+			output += out + "\n"
+			responseItemErrors, _ := serverapi.ResponseItems2ResponseItemErrors(response)
+			if len(responseItemErrors) == 0 {
+				deploy.reportClusterError(server, response.Message)
 			}
-			for applicationResultIndex := range applicationResults {
-				out, err := serverapi.ApplicationResult2MessageString(applicationResults[applicationResultIndex])
-				if err != nil {
-					return out, err
-				}
-				output += out
+			for i := range responseItemErrors {
+				journal = append(journal, deploy.journalResponseItemError(server, responseItemErrors[i]))
+				deploy.reportResponseItemError(server, responseItemErrors[i])
 			}
+			continue
+		}
+
+		applicationResults, err := serverapi.ResponseItems2ApplicationResults(response)
+		if err != nil {
+			return output, err
 		}
+		for applicationResultIndex := range applicationResults {
+			entry := deploy.journalApplicationResult(applicationResults[applicationResultIndex])
+			journal = append(journal, entry)
+			deploy.reportApplicationResult(entry)
 
+			out, err := serverapi.ApplicationResult2MessageString(applicationResults[applicationResultIndex])
+			if err != nil {
+				return output + out, err
+			}
+			output += out
+		}
 	}
 
-	return
+	return output, nil
 }
 
 func (deploy *DeployClass) populateFlagsEnvAppList(appList []string, envList []string) (err error) {
@@ -180,12 +314,7 @@ func (deploy *DeployClass) populateFlagsEnvAppList(appList []string, envList []s
 
 func (deploy *DeployClass) populateAllAppForEnv(env string) (err error) {
 
-	auroraConfig, err := auroraconfig.GetAuroraConfig(deploy.Configuration)
-	if err != nil {
-		return err
-	}
-
-	for filename := range auroraConfig.Files {
+	for filename := range deploy.auroraConfig.Files {
 		if strings.Contains(filename, "/") {
 			// We have a full path name
 			parts := strings.Split(filename, "/")
@@ -204,12 +333,7 @@ func (deploy *DeployClass) populateAllAppForEnv(env string) (err error) {
 
 func (deploy *DeployClass) populateAllEnvForApp(app string) (err error) {
 
-	auroraConfig, err := auroraconfig.GetAuroraConfig(deploy.Configuration)
-	if err != nil {
-		return err
-	}
-
-	for filename := range auroraConfig.Files {
+	for filename := range deploy.auroraConfig.Files {
 		if strings.Contains(filename, "/") {
 			// We have a full path name
 			parts := strings.Split(filename, "/")
@@ -233,9 +357,18 @@ func (deploy *DeployClass) validateDeploy(args []string, appList []string, envLi
 		}
 	}
 
-	err = deploy.fuzzyArgs.Init(deploy.Configuration)
-	if err != nil {
-		return err
+	if deploy.FromLocal != "" {
+		deploy.fuzzyArgs.InitWithAuroraConfig(deploy.Configuration, *deploy.auroraConfig)
+	} else {
+		err = deploy.fuzzyArgs.Init(deploy.Configuration)
+		if err != nil {
+			return err
+		}
+	}
+
+	args, selectors := fuzzyargs.SplitSelectors(args)
+	for _, exclude := range deploy.Excludes {
+		selectors = append(selectors, "!"+strings.TrimPrefix(exclude, "!"))
 	}
 
 	if deployAll {
@@ -272,7 +405,28 @@ func (deploy *DeployClass) validateDeploy(args []string, appList []string, envLi
 		}
 	}
 
-	if len(deploy.fuzzyArgs.GetEnvs()) > 1 || len(deploy.fuzzyArgs.GetApps()) > 1 {
+	if len(selectors) > 0 {
+		// Resolve the selectors into a list of env/app deployments, starting with what the fuzzy arguments matched
+		var base []string
+		if len(deploy.fuzzyArgs.GetEnvs()) > 0 && len(deploy.fuzzyArgs.GetApps()) > 0 {
+			for _, env := range deploy.fuzzyArgs.GetEnvs() {
+				for _, app := range deploy.fuzzyArgs.GetApps() {
+					base = append(base, env+"/"+app)
+				}
+			}
+		}
+		deployments, err := deploy.fuzzyArgs.SelectDeployments(base, selectors)
+		if err != nil {
+			return err
+		}
+		if len(deployments) == 0 {
+			return errors.New("No deployments matched the selection")
+		}
+		deploy.fuzzyArgs.SetDeployments(deployments)
+	}
+
+	if len(deploy.fuzzyArgs.GetEnvs()) > 1 || len(deploy.fuzzyArgs.GetApps()) > 1 || len(selectors) > 0 ||
+		(deploy.ShowEffective && len(deploy.effectiveFiles) > 0) {
 		if !force {
 			response, err := executil.PromptYNC(deploy.fuzzyArgs.GetDeploymentSummaryString() + "Are you sure?")
 			//			response, err := executil.PromptYNC("This will deploy " + strconv.Itoa(len(deploy.appList)) + " applications in " + strconv.Itoa(len(deploy.envList)) + " environments.  Are you sure?")
@@ -280,8 +434,7 @@ func (deploy *DeployClass) validateDeploy(args []string, appList []string, envLi
 				return err
 			}
 			if response != "Y" {
-				err = errors.New("Operation cancelled by user")
-				return err
+				return errCancelled
 			}
 		}
 	}
@@ -47,6 +47,11 @@ func (deploy *DeployClass) updateVersion(deployVersion string) (err error) {
 		}
 	}
 
+	if deploy.FromLocal != "" {
+		// The version is only changed in the local files sent with the deploy
+		return nil
+	}
+
 	err = auroraconfig.PutAuroraConfig(*deploy.auroraConfig, deploy.Configuration)
 	if err != nil {
 		return err
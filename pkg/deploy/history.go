@@ -0,0 +1,175 @@
+package deploy
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/printutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+	"github.com/spf13/viper"
+)
+
+// The deploy journal is a local file with one JSON entry per line, one entry per deployed application
+const journalFilename = "/.ao-deploy-history.json"
+
+type JournalEntry struct {
+	Time          time.Time                  `json:"time"`
+	User          string                     `json:"user"`
+	Affiliation   string                     `json:"affiliation"`
+	Env           string                     `json:"env"`
+	App           string                     `json:"app"`
+	Version       string                     `json:"version"`
+	Cluster       string                     `json:"cluster"`
+	OperationType string                     `json:"operationType"`
+	Success       bool                       `json:"success"`
+	Message       string                     `json:"message,omitempty"`
+	Overrides     map[string]json.RawMessage `json:"overrides,omitempty"`
+}
+
+func getJournalLocation() string {
+	viper.BindEnv("HOME")
+	return viper.GetString("HOME") + journalFilename
+}
+
+func getUser() string {
+	viper.BindEnv("USER")
+	return viper.GetString("USER")
+}
+
+func (deploy *DeployClass) newJournalEntry(env string, app string) JournalEntry {
+	return JournalEntry{
+		Time:        time.Now(),
+		User:        getUser(),
+		Affiliation: deploy.Configuration.GetAffiliation(),
+		Env:         env,
+		App:         app,
+		Overrides:   deploy.overrides,
+	}
+}
+
+func (deploy *DeployClass) journalApplicationResult(applicationResult serverapi.ApplicationResult) JournalEntry {
+	entry := deploy.newJournalEntry(applicationResult.AuroraDc.EnvName, applicationResult.AuroraDc.Name)
+	if entry.Env == "" {
+		entry.Env = applicationResult.ApplicationId.EnvironmentName
+	}
+	if entry.App == "" {
+		entry.App = applicationResult.ApplicationId.ApplicationName
+	}
+	entry.Version = applicationResult.AuroraDc.Version
+	entry.Cluster = applicationResult.AuroraDc.Cluster
+	entry.OperationType = applicationResult.OpenShiftResponse.OperationType
+	entry.Success = true
+	return entry
+}
+
+func (deploy *DeployClass) journalResponseItemError(cluster string, responseItemError serverapi.ResponseItemError) JournalEntry {
+	entry := deploy.newJournalEntry(responseItemError.Environment, responseItemError.Application)
+	entry.Cluster = cluster
+	var messages []string
+	for _, message := range responseItemError.Messages {
+		messages = append(messages, message.Message)
+	}
+	entry.Message = strings.Join(messages, "; ")
+	return entry
+}
+
+// Appends entries to the deploy journal.  The journal is a convenience, so failing to write it must not fail the deploy.
+func writeJournal(entries []JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(getJournalLocation(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReadJournal() (entries []JournalEntry, err error) {
+	file, err := os.Open(getJournalLocation())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip lines we do not understand rather than hiding the rest of the history
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Lists the deploy journal for an affiliation, newest first.
+// The filter is matched against env/app, and limit 0 means no limit.
+func History(affiliation string, filter string, limit int) (output string, err error) {
+	entries, err := ReadJournal()
+	if err != nil {
+		return "", err
+	}
+
+	var times, users, deployments, versions, clusters, operations, overrides []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Affiliation != affiliation {
+			continue
+		}
+		if filter != "" && !strings.Contains(entry.Env+"/"+entry.App, filter) {
+			continue
+		}
+		if limit > 0 && len(times) >= limit {
+			break
+		}
+
+		operation := entry.OperationType
+		if !entry.Success {
+			operation = "FAILED"
+		}
+		var overriddenFiles []string
+		for filename := range entry.Overrides {
+			overriddenFiles = append(overriddenFiles, filename)
+		}
+		sort.Strings(overriddenFiles)
+
+		times = append(times, entry.Time.Local().Format("2006-01-02 15:04:05"))
+		users = append(users, entry.User)
+		deployments = append(deployments, entry.Env+"/"+entry.App)
+		versions = append(versions, entry.Version)
+		clusters = append(clusters, entry.Cluster)
+		operations = append(operations, operation)
+		overrides = append(overrides, strings.Join(overriddenFiles, ","))
+	}
+
+	if len(times) == 0 {
+		return "No deploys recorded for affiliation " + affiliation, nil
+	}
+
+	headers := []string{"TIME", "USER", "DEPLOYMENT", "VERSION", "CLUSTER", "OPERATION", "OVERRIDES"}
+	output = printutil.FormatTable(headers, times, users, deployments, versions, clusters, operations, overrides)
+	output += strconv.Itoa(len(times)) + " entries"
+	return output, nil
+}
@@ -0,0 +1,62 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+// Reads the AuroraConfig to deploy from a checked out repository, and lists how it differs from the one in Boober
+func (deploy *DeployClass) loadLocalAuroraConfig() error {
+	local, root, err := auroraconfig.ReadLocalAuroraConfig(deploy.FromLocal)
+	if err != nil {
+		return err
+	}
+	deploy.auroraConfig = &local
+
+	fmt.Println("Deploying the local AuroraConfig in " + root)
+	stored, err := auroraconfig.GetAuroraConfig(deploy.Configuration)
+	if err != nil {
+		fmt.Println("Unable to compare with the AuroraConfig in Boober: " + err.Error())
+		return nil
+	}
+	changes := localChanges(stored, local)
+	if len(changes) == 0 {
+		fmt.Println("The local files are the same as in Boober")
+	}
+	for _, change := range changes {
+		fmt.Println("\t" + change)
+	}
+	return nil
+}
+
+// Lists the files that are new, changed or removed in the local AuroraConfig
+func localChanges(stored serverapi.AuroraConfig, local serverapi.AuroraConfig) (changes []string) {
+	for filename, content := range local.Files {
+		storedContent, found := stored.Files[filename]
+		if !found {
+			changes = append(changes, filename+" (new)")
+		} else if !sameJson(storedContent, content) {
+			changes = append(changes, filename+" (changed)")
+		}
+	}
+	for filename := range stored.Files {
+		if _, found := local.Files[filename]; !found {
+			changes = append(changes, filename+" (removed)")
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func sameJson(a json.RawMessage, b json.RawMessage) bool {
+	var aValue, bValue interface{}
+	if json.Unmarshal(a, &aValue) != nil || json.Unmarshal(b, &bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
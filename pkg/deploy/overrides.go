@@ -0,0 +1,60 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/skatteetaten/ao/pkg/jsonutil"
+)
+
+// Validates the overrides given on the command line and applies them to the files in the AuroraConfig.
+// Overrides may be RFC 7396 merge patches or RFC 6902 JSON Patches.  Several overrides for the same file
+// are applied in the order given.  The override sent to Boober is the merge patch between the stored file
+// and the effective file, so Boober sees a plain override regardless of the patch format used.
+func (deploy *DeployClass) prepareOverrides() (err error) {
+	overrides, err := jsonutil.ParseOverrides(deploy.overrideJsons, deploy.OverrideFiles)
+	if err != nil {
+		return err
+	}
+
+	deploy.effectiveFiles = make(map[string]json.RawMessage)
+	deploy.overrides = make(map[string]json.RawMessage)
+
+	for _, override := range overrides {
+		content, found := deploy.effectiveFiles[override.Filename]
+		if !found {
+			content, found = deploy.auroraConfig.Files[override.Filename]
+			if !found {
+				return errors.New("Override for " + override.Filename + ": No such file in AuroraConfig")
+			}
+		}
+		content, err = jsonutil.ApplyPatch(content, override.Patch)
+		if err != nil {
+			return errors.New("Override for " + override.Filename + ": " + err.Error())
+		}
+		deploy.effectiveFiles[override.Filename] = content
+	}
+
+	for filename, content := range deploy.effectiveFiles {
+		deploy.overrides[filename], err = jsonutil.CreateMergePatch(deploy.auroraConfig.Files[filename], content)
+		if err != nil {
+			return err
+		}
+	}
+	return
+}
+
+func (deploy *DeployClass) getEffectiveFilesString() (output string) {
+	var filenames []string
+	for filename := range deploy.effectiveFiles {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		output += fmt.Sprintf("%v (effective):\n%v\n", filename, jsonutil.PrettyPrintJson(string(deploy.effectiveFiles[filename])))
+	}
+	return output
+}
@@ -0,0 +1,153 @@
+package deploy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+// Exit codes for the deploy command, so that pipelines can tell the failures apart
+const (
+	ExitSuccess           = 0
+	ExitDeployFailure     = 1
+	ExitValidationFailure = 2
+	ExitPartialFailure    = 3
+	ExitAuthFailure       = 4
+)
+
+var exitMessages = map[int]string{
+	ExitDeployFailure:     "Deploy failed",
+	ExitValidationFailure: "Deploy failed: The configuration is not valid",
+	ExitPartialFailure:    "Deploy failed for some of the applications",
+	ExitAuthFailure:       "Deploy failed: Not authorized, please log in again",
+}
+
+// Failure types in the report
+const (
+	FailureValidation = "validation"
+	FailureCluster    = "cluster"
+	FailureAuth       = "auth"
+)
+
+type ReportEntry struct {
+	Cluster       string   `json:"cluster"`
+	Env           string   `json:"env,omitempty"`
+	App           string   `json:"app,omitempty"`
+	Version       string   `json:"version,omitempty"`
+	OperationType string   `json:"operationType,omitempty"`
+	Success       bool     `json:"success"`
+	Failure       string   `json:"failure,omitempty"`
+	Messages      []string `json:"messages,omitempty"`
+}
+
+// The report has one entry per ApplicationResult and one per error reported by a cluster
+type DeployReport struct {
+	Time        time.Time     `json:"time"`
+	Affiliation string        `json:"affiliation"`
+	Success     bool          `json:"success"`
+	ExitCode    int           `json:"exitCode"`
+	Error       string        `json:"error,omitempty"`
+	Results     []ReportEntry `json:"results"`
+}
+
+func (deploy *DeployClass) reportApplicationResult(entry JournalEntry) {
+	deploy.report = append(deploy.report, ReportEntry{
+		Cluster:       entry.Cluster,
+		Env:           entry.Env,
+		App:           entry.App,
+		Version:       entry.Version,
+		OperationType: entry.OperationType,
+		Success:       true,
+	})
+}
+
+func (deploy *DeployClass) reportResponseItemError(cluster string, responseItemError serverapi.ResponseItemError) {
+	entry := ReportEntry{
+		Cluster: cluster,
+		Env:     responseItemError.Environment,
+		App:     responseItemError.Application,
+		Failure: FailureValidation,
+	}
+	for _, message := range responseItemError.Messages {
+		text := message.Message
+		if message.Field.Path != "" {
+			text = message.Field.Path + " (" + message.Field.Value + ") in " + message.Field.Source + ": " + text
+		}
+		entry.Messages = append(entry.Messages, text)
+	}
+	deploy.report = append(deploy.report, entry)
+}
+
+// Records a failure without application details, such as a connection error or a rejected token
+func (deploy *DeployClass) reportClusterError(cluster string, message string) {
+	failure := FailureCluster
+	if serverapi.IsUnauthorized(message) {
+		failure = FailureAuth
+	}
+	deploy.report = append(deploy.report, ReportEntry{
+		Cluster:  cluster,
+		Failure:  failure,
+		Messages: []string{message},
+	})
+}
+
+// Finds the exit code from the results reported by the clusters.
+// A rejected token takes precedence, then a mix of successes and failures is a partial failure.
+func (deploy *DeployClass) resultExitCode() int {
+	var succeeded, failed, invalid, unauthorized int
+	for _, entry := range deploy.report {
+		switch {
+		case entry.Success:
+			succeeded++
+		case entry.Failure == FailureAuth:
+			unauthorized++
+		case entry.Failure == FailureValidation:
+			invalid++
+		}
+		if !entry.Success {
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return ExitSuccess
+	case unauthorized > 0:
+		return ExitAuthFailure
+	case succeeded > 0:
+		return ExitPartialFailure
+	case invalid == failed:
+		return ExitValidationFailure
+	}
+	return ExitDeployFailure
+}
+
+// Returns the exit code of the last ExecuteDeploy
+func (deploy *DeployClass) ExitCode() int {
+	return deploy.exitCode
+}
+
+// Writes the JSON report of the last ExecuteDeploy.  The report is written for failed deploys as well.
+func (deploy *DeployClass) WriteReport(filename string, deployErr error) error {
+	report := DeployReport{
+		Time:        time.Now(),
+		Affiliation: deploy.Configuration.GetAffiliation(),
+		Success:     deploy.exitCode == ExitSuccess && deployErr == nil,
+		ExitCode:    deploy.exitCode,
+		Results:     deploy.report,
+	}
+	if deployErr != nil {
+		report.Error = deployErr.Error()
+	}
+	if report.Results == nil {
+		report.Results = []ReportEntry{}
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(content, '\n'), 0600)
+}
@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"testing"
+)
+
+func TestResultExitCode(t *testing.T) {
+	ok := ReportEntry{Cluster: "utv", Success: true}
+	invalid := ReportEntry{Cluster: "utv", Failure: FailureValidation}
+	clusterError := ReportEntry{Cluster: "prod", Failure: FailureCluster}
+	unauthorized := ReportEntry{Cluster: "prod", Failure: FailureAuth}
+
+	testCases := []struct {
+		report   []ReportEntry
+		expected int
+	}{
+		{nil, ExitSuccess},
+		{[]ReportEntry{ok, ok}, ExitSuccess},
+		{[]ReportEntry{invalid}, ExitValidationFailure},
+		{[]ReportEntry{clusterError}, ExitDeployFailure},
+		{[]ReportEntry{invalid, clusterError}, ExitDeployFailure},
+		{[]ReportEntry{ok, clusterError}, ExitPartialFailure},
+		{[]ReportEntry{ok, invalid}, ExitPartialFailure},
+		{[]ReportEntry{ok, unauthorized}, ExitAuthFailure},
+	}
+
+	for i, testCase := range testCases {
+		deploy := DeployClass{report: testCase.report}
+		if exitCode := deploy.resultExitCode(); exitCode != testCase.expected {
+			t.Errorf("Case %d: resultExitCode returned %d, expected %d", i, exitCode, testCase.expected)
+		}
+	}
+}
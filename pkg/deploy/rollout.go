@@ -0,0 +1,307 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/cmdoptions"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/executil"
+	"github.com/skatteetaten/ao/pkg/fuzzyargs"
+	"github.com/skatteetaten/ao/pkg/printutil"
+)
+
+const (
+	StagePending  = "PENDING"
+	StageDeployed = "DEPLOYED"
+	StageDone     = "DONE"
+	StageFailed   = "FAILED"
+)
+
+const rolloutPollInterval = 5 * time.Second
+
+type RolloutStage struct {
+	Name    string    `json:"name"`
+	Env     string    `json:"env"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time,omitempty"`
+}
+
+// The state of a rollout is saved after every stage, so that a stopped rollout can be resumed
+type RolloutState struct {
+	Affiliation string         `json:"affiliation"`
+	App         string         `json:"app"`
+	Version     string         `json:"version,omitempty"`
+	Stages      []RolloutStage `json:"stages"`
+}
+
+type RolloutClass struct {
+	Configuration *configuration.ConfigurationClass
+	StateFile     string
+	Timeout       time.Duration
+	ProbeUrl      string
+	state         RolloutState
+}
+
+func (rollout *RolloutClass) saveState() error {
+	content, err := json.MarshalIndent(rollout.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(rollout.StateFile, content, 0600)
+}
+
+func (rollout *RolloutClass) loadState() error {
+	content, err := ioutil.ReadFile(rollout.StateFile)
+	if err != nil {
+		return errors.New("Unable to read rollout state " + rollout.StateFile + ": " + err.Error())
+	}
+	return json.Unmarshal(content, &rollout.state)
+}
+
+// Resolves the app and the stage names against the AuroraConfig, so that all stages are validated before the first deploy
+func (rollout *RolloutClass) newState(app string, stages []string, version string) (err error) {
+	var fuzzyArgs fuzzyargs.FuzzyArgs
+	if err := fuzzyArgs.Init(rollout.Configuration); err != nil {
+		return err
+	}
+
+	rollout.state.Affiliation = rollout.Configuration.GetAffiliation()
+	rollout.state.Version = version
+	rollout.state.App, err = fuzzyArgs.GetFuzzyApp(app)
+	if err != nil {
+		return err
+	}
+	if rollout.state.App == "" {
+		return errors.New(app + ": not found")
+	}
+
+	for _, stage := range stages {
+		env, err := fuzzyArgs.GetFuzzyEnv(stage)
+		if err != nil {
+			return err
+		}
+		if env == "" {
+			return errors.New("Stage " + stage + ": No matching environment")
+		}
+		if !fuzzyArgs.IsLegalFile(env + "/" + rollout.state.App + ".json") {
+			return errors.New("Stage " + stage + ": " + rollout.state.App + " is not deployed in " + env)
+		}
+		rollout.state.Stages = append(rollout.state.Stages, RolloutStage{Name: stage, Env: env, Status: StagePending})
+	}
+	return nil
+}
+
+// Deploys the app stage by stage.  After each stage the rollout waits for the DeploymentConfig to be healthy,
+// and requires either a successful health probe or a confirmation before the next stage is started.
+// If resume is true, the stages are read from the state file and the completed stages are skipped.
+func (rollout *RolloutClass) ExecuteRollout(app string, stages []string, version string, resume bool,
+	persistentOptions *cmdoptions.CommonCommandOptions) (output string, err error) {
+
+	if resume {
+		if err := rollout.loadState(); err != nil {
+			return "", err
+		}
+		if rollout.state.Affiliation != rollout.Configuration.GetAffiliation() {
+			return "", errors.New("The rollout in " + rollout.StateFile + " belongs to affiliation " + rollout.state.Affiliation)
+		}
+		if !strings.Contains(rollout.state.App, app) {
+			return "", errors.New("The rollout in " + rollout.StateFile + " is for " + rollout.state.App)
+		}
+	} else {
+		if len(stages) == 0 {
+			return "", errors.New("No stages given")
+		}
+		if err := rollout.newState(app, stages, version); err != nil {
+			return "", err
+		}
+	}
+
+	for i := range rollout.state.Stages {
+		stage := &rollout.state.Stages[i]
+		if stage.Status == StageDone {
+			continue
+		}
+
+		var err error
+		if stage.Status == StageDeployed {
+			// Deployed before the rollout was stopped, so only the health check is left
+			fmt.Println("Stage " + stage.Name + ": Waiting for " + stage.Env + "/" + rollout.state.App)
+			err = rollout.waitForRollout(stage.Env, rollout.state.App)
+		} else {
+			if i > 0 {
+				if err := rollout.gate(rollout.state.Stages[i-1]); err != nil {
+					stage.Message = err.Error()
+					rollout.saveState()
+					return rollout.Report(), err
+				}
+			}
+
+			fmt.Println("Stage " + stage.Name + ": Deploying " + stage.Env + "/" + rollout.state.App)
+			err = rollout.deployStage(stage, persistentOptions)
+		}
+		if err != nil {
+			stage.Status = StageFailed
+			stage.Message = err.Error()
+			stage.Time = time.Now()
+			rollout.saveState()
+			return rollout.Report(), errors.New("Rollout stopped at stage " + stage.Name + ", resume with --resume when fixed")
+		}
+		stage.Status = StageDone
+		stage.Message = ""
+		stage.Time = time.Now()
+		if err := rollout.saveState(); err != nil {
+			return rollout.Report(), err
+		}
+	}
+
+	return rollout.Report(), nil
+}
+
+func (rollout *RolloutClass) deployStage(stage *RolloutStage, persistentOptions *cmdoptions.CommonCommandOptions) error {
+	deploy := DeployClass{
+		Configuration: rollout.Configuration,
+	}
+	deployment := stage.Env + "/" + rollout.state.App
+	output, err := deploy.ExecuteDeploy([]string{deployment}, nil, nil, nil, persistentOptions,
+		false, false, true, rollout.state.Version, "")
+	if output != "" {
+		fmt.Println(output)
+	}
+	if err != nil {
+		return err
+	}
+	stage.Status = StageDeployed
+	rollout.saveState()
+
+	return rollout.waitForRollout(stage.Env, rollout.state.App)
+}
+
+// Waits until the DeploymentConfig runs the configured version with all replicas updated and available
+func (rollout *RolloutClass) waitForRollout(env string, app string) error {
+	ac, err := auroraconfig.GetAuroraConfig(rollout.Configuration)
+	if err != nil {
+		return err
+	}
+	spec, err := auroraconfig.GetDeploymentSpec(ac, env, app)
+	if err != nil {
+		return err
+	}
+
+	name := auroraconfig.GetSpecField(spec, "name")
+	deadline := time.Now().Add(rollout.Timeout)
+	for {
+		status := getDeploymentStatus(env, app, spec, rollout.Configuration)
+		healthy, message := rollout.checkHealth(status, name)
+		if healthy {
+			fmt.Println("Stage " + env + ": " + app + " " + status.LiveVersion + " is running in " + status.Cluster)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("Timed out waiting for rollout: " + message)
+		}
+		time.Sleep(rolloutPollInterval)
+	}
+}
+
+func (rollout *RolloutClass) checkHealth(status DeploymentStatus, name string) (healthy bool, message string) {
+	switch status.Status {
+	case StatusUnknown:
+		return false, status.Message
+	case StatusNotDeployed:
+		return false, "DeploymentConfig not found in " + status.Cluster
+	case StatusDiffers:
+		return false, "Running version " + status.LiveVersion + ", expected " + status.ConfigVersion
+	}
+
+	cluster := rollout.Configuration.OpenshiftConfig.GetCluster(status.Cluster)
+	dc, err := cluster.GetDeploymentConfig(status.Namespace, name)
+	if err != nil || dc == nil {
+		return false, "Unable to read DeploymentConfig"
+	}
+	if dc.Status.ObservedGeneration < dc.Metadata.Generation {
+		return false, "DeploymentConfig change not yet observed"
+	}
+	if dc.Status.UpdatedReplicas < dc.Spec.Replicas || dc.Status.AvailableReplicas < dc.Spec.Replicas || dc.Status.UnavailableReplicas > 0 {
+		return false, fmt.Sprintf("%d of %d replicas updated and available", dc.Status.AvailableReplicas, dc.Spec.Replicas)
+	}
+	return true, ""
+}
+
+// Before the next stage starts, the previous stage must pass the health probe if one is given,
+// otherwise the user must confirm.  The probe is retried until it passes or the rollout timeout is reached.
+func (rollout *RolloutClass) gate(previous RolloutStage) error {
+	if rollout.ProbeUrl != "" {
+		url := strings.Replace(strings.Replace(rollout.ProbeUrl, "{env}", previous.Env, -1), "{app}", rollout.state.App, -1)
+		deadline := time.Now().Add(rollout.Timeout)
+		for {
+			err := probe(url, deadline)
+			if err == nil {
+				fmt.Println("Stage " + previous.Name + ": Health probe " + url + " OK")
+				return nil
+			}
+			if time.Now().Add(rolloutPollInterval).After(deadline) {
+				return errors.New("Health probe " + url + " failed: " + err.Error())
+			}
+			time.Sleep(rolloutPollInterval)
+		}
+	}
+
+	response, err := executil.PromptYNC("Stage " + previous.Name + " is done.  Continue with the next stage?")
+	if err != nil {
+		return err
+	}
+	if response != "Y" {
+		return errors.New("Rollout paused by user, resume with --resume")
+	}
+	return nil
+}
+
+// Calls the health probe once, giving up at the deadline
+func probe(url string, deadline time.Time) error {
+	timeout := time.Until(deadline)
+	if timeout < rolloutPollInterval {
+		timeout = rolloutPollInterval
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func (rollout *RolloutClass) Report() string {
+	var names, envs, statuses, times, messages []string
+	for _, stage := range rollout.state.Stages {
+		names = append(names, stage.Name)
+		envs = append(envs, stage.Env)
+		statuses = append(statuses, stage.Status)
+		if stage.Time.IsZero() {
+			times = append(times, "")
+		} else {
+			times = append(times, stage.Time.Local().Format("2006-01-02 15:04:05"))
+		}
+		messages = append(messages, stage.Message)
+	}
+	headers := []string{"STAGE", "ENVIRONMENT", "STATUS", "TIME", "MESSAGE"}
+	return "Rollout of " + rollout.state.App + ":\n" + printutil.FormatTable(headers, names, envs, statuses, times, messages)
+}
+
+// Returns the default state file for an app, in the current folder
+func DefaultRolloutStateFile(app string) string {
+	wd, _ := os.Getwd()
+	return wd + "/.ao-rollout-" + app + ".json"
+}
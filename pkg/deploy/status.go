@@ -0,0 +1,118 @@
+package deploy
+
+import (
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/printutil"
+)
+
+const (
+	StatusInSync      = "IN SYNC"
+	StatusDiffers     = "DIFFERS"
+	StatusNotDeployed = "NOT DEPLOYED"
+	StatusUnknown     = "UNKNOWN"
+)
+
+type DeploymentStatus struct {
+	Env           string
+	App           string
+	Cluster       string
+	Namespace     string
+	ConfigVersion string
+	LiveVersion   string
+	Status        string
+	Message       string
+}
+
+// Compares the version in the AuroraConfig with the version of the running DeploymentConfig for each deployment
+// matching the filter.  An empty filter will report all deployments in the affiliation.
+func GetDeploymentStatuses(filter string, config *configuration.ConfigurationClass) (statuses []DeploymentStatus, err error) {
+	ac, err := auroraconfig.GetAuroraConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deployment := range auroraconfig.GetDeployments(ac) {
+		if filter != "" && !strings.Contains(deployment, filter) {
+			continue
+		}
+		parts := strings.Split(deployment, "/")
+		spec, err := auroraconfig.GetDeploymentSpec(ac, parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, getDeploymentStatus(parts[0], parts[1], spec, config))
+	}
+	return statuses, nil
+}
+
+func getDeploymentStatus(env string, app string, spec map[string]interface{}, config *configuration.ConfigurationClass) (status DeploymentStatus) {
+	status.Env = env
+	status.App = app
+	status.Cluster = auroraconfig.GetSpecField(spec, "cluster")
+	status.ConfigVersion = auroraconfig.GetSpecField(spec, "version")
+
+	affiliation := auroraconfig.GetSpecField(spec, "affiliation")
+	if affiliation == "" {
+		affiliation = config.GetAffiliation()
+	}
+	status.Namespace = affiliation + "-" + auroraconfig.GetSpecField(spec, "envName")
+	name := auroraconfig.GetSpecField(spec, "name")
+
+	cluster := config.OpenshiftConfig.GetCluster(status.Cluster)
+	if cluster == nil || !cluster.Reachable {
+		status.Status = StatusUnknown
+		status.Message = "Cluster " + status.Cluster + " is not reachable"
+		return status
+	}
+
+	dc, err := cluster.GetDeploymentConfig(status.Namespace, name)
+	if err != nil {
+		status.Status = StatusUnknown
+		status.Message = err.Error()
+		return status
+	}
+	if dc == nil {
+		status.Status = StatusNotDeployed
+		return status
+	}
+
+	status.LiveVersion, err = cluster.GetDeployedVersion(dc)
+	if err != nil {
+		status.Status = StatusUnknown
+		status.Message = err.Error()
+		return status
+	}
+	if status.LiveVersion == status.ConfigVersion {
+		status.Status = StatusInSync
+	} else {
+		status.Status = StatusDiffers
+	}
+	return status
+}
+
+func Status(filter string, config *configuration.ConfigurationClass) (output string, err error) {
+	statuses, err := GetDeploymentStatuses(filter, config)
+	if err != nil {
+		return "", err
+	}
+	if len(statuses) == 0 {
+		return "No deployments matching " + filter, nil
+	}
+
+	var deployments, clusters, configVersions, liveVersions, states, messages []string
+	for _, status := range statuses {
+		deployments = append(deployments, status.Env+"/"+status.App)
+		clusters = append(clusters, status.Cluster)
+		configVersions = append(configVersions, status.ConfigVersion)
+		liveVersions = append(liveVersions, status.LiveVersion)
+		states = append(states, status.Status)
+		messages = append(messages, status.Message)
+	}
+
+	headers := []string{"DEPLOYMENT", "CLUSTER", "CONFIG VERSION", "LIVE VERSION", "STATUS", "MESSAGE"}
+	output = printutil.FormatTable(headers, deployments, clusters, configVersions, liveVersions, states, messages)
+	return output, nil
+}
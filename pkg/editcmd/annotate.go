@@ -0,0 +1,183 @@
+package editcmd
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+// A frame in the JSON being scanned: an object or an array, with the path of the container
+type jsonFrame struct {
+	path      string
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+func (frame *jsonFrame) childPath() string {
+	if frame.object {
+		return frame.path + "/" + frame.key
+	}
+	return frame.path + "/" + strconv.Itoa(frame.index)
+}
+
+func (frame *jsonFrame) valueDone() {
+	if frame.object {
+		frame.expectKey = true
+	} else {
+		frame.index++
+	}
+}
+
+// Finds the line of each key in the JSON content, by the path of the key, like /config/JAVA_OPTS.
+// Array elements are numbered from 0.  If the content is not legal JSON, the keys found before the error are returned.
+func jsonKeyLines(content string) (keyLines map[string]int) {
+	keyLines = make(map[string]int)
+	decoder := json.NewDecoder(strings.NewReader(content))
+	var stack []*jsonFrame
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return keyLines
+		}
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if delim, isDelim := token.(json.Delim); isDelim {
+			switch delim {
+			case '{', '[':
+				frame := &jsonFrame{object: delim == '{', expectKey: delim == '{'}
+				if top != nil {
+					frame.path = top.childPath()
+				}
+				stack = append(stack, frame)
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 {
+					stack[len(stack)-1].valueDone()
+				}
+			}
+			continue
+		}
+
+		if top != nil && top.object && top.expectKey {
+			top.key, _ = token.(string)
+			top.expectKey = false
+			path := top.childPath()
+			if _, found := keyLines[path]; !found {
+				keyLines[path] = strings.Count(content[:decoder.InputOffset()], "\n")
+			}
+		} else if top != nil {
+			top.valueDone()
+		}
+	}
+}
+
+// Boober may give the path with or without the leading slash
+func normalizePath(path string) string {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return path
+	}
+	return "/" + path
+}
+
+// The messages from all the applications, without duplicates.  A message about a shared file, like about.json,
+// is often repeated for each application.
+func validationMessageList(validationError *auroraconfig.ValidationError) (messages []serverapi.ResponseItemMessage) {
+	seen := make(map[string]bool)
+	for _, itemError := range validationError.ItemErrors {
+		for _, message := range itemError.Messages {
+			key := message.Field.Source + "|" + message.Field.Path + "|" + message.Message
+			if !seen[key] {
+				seen[key] = true
+				messages = append(messages, message)
+			}
+		}
+	}
+	return messages
+}
+
+func formatValidationMessage(message serverapi.ResponseItemMessage, defaultSource string) string {
+	source := message.Field.Source
+	if source == "" {
+		source = defaultSource
+	}
+	text := message.Message
+	if message.Field.Path != "" {
+		text = message.Field.Path + " (" + message.Field.Value + "): " + text
+	}
+	if source != "" {
+		text = source + ": " + text
+	}
+	return text
+}
+
+// Adds the messages about the JSON file as comments above the keys they refer to.  Returns which of
+// the messages were placed.
+func placeJsonMessages(content string, filename string, messages []serverapi.ResponseItemMessage) (annotated string, placed []bool) {
+	placed = make([]bool, len(messages))
+	keyLines := jsonKeyLines(content)
+	lineComments := make(map[int][]string)
+	for i, message := range messages {
+		if message.Field.Source != "" && message.Field.Source != filename {
+			continue
+		}
+		line, found := keyLines[normalizePath(message.Field.Path)]
+		if !found {
+			continue
+		}
+		text := message.Message
+		if message.Field.Value != "" {
+			text += " (" + message.Field.Value + ")"
+		}
+		lineComments[line] = append(lineComments[line], text)
+		placed[i] = true
+	}
+	if len(lineComments) == 0 {
+		return content, placed
+	}
+
+	lines := strings.Split(content, "\n")
+	var output []string
+	for i, line := range lines {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		for _, comment := range lineComments[i] {
+			output = append(output, indent+commentString+comment)
+		}
+		output = append(output, line)
+	}
+	return strings.Join(output, "\n"), placed
+}
+
+// Adds the validation messages to the content.  The messages that the format can place by field are put
+// above the field, the rest are put at the top, labeled with the file they refer to.
+func annotateContent(content string, contentName string, format editFormat, validationMessages string, storeErr error) string {
+	validationError, isValidationError := storeErr.(*auroraconfig.ValidationError)
+	if !isValidationError || format.placeMessages == nil || len(validationError.ItemErrors) == 0 {
+		annotated, _ := addComments(content, validationMessages)
+		return annotated
+	}
+
+	messages := validationMessageList(validationError)
+	annotated, placed := format.placeMessages(content, contentName, messages)
+
+	var topMessages []string
+	if validationError.Message != "" {
+		topMessages = append(topMessages, validationError.Message)
+	}
+	for i, message := range messages {
+		if !placed[i] {
+			topMessages = append(topMessages, formatValidationMessage(message, contentName))
+		}
+	}
+	if len(topMessages) > 0 {
+		annotated, _ = addComments(annotated, strings.Join(topMessages, "\n"))
+	}
+	return annotated
+}
@@ -29,24 +29,32 @@ func (editcmd *EditcmdClass) FuzzyEditFile(args []string) (string, error) {
 	var fuzzyArgs fuzzyargs.FuzzyArgs
 	fuzzyArgs.Init(editcmd.Configuration)
 
-	if err := fuzzyArgs.PopulateFuzzyFile(args); err != nil {
+	if err := fuzzyArgs.PopulateFuzzyFiles(args); err != nil {
 		return "", err
 	}
 
-	filename, err := fuzzyArgs.GetFile()
-	if err != nil {
-		return "", err
+	filenames := fuzzyArgs.GetFiles()
+	if len(filenames) == 0 {
+		return "", errors.New("Not found")
 	}
-
-	return editcmd.EditFile(filename)
+	if len(filenames) == 1 {
+		return editcmd.EditFile(filenames[0])
+	}
+	return editcmd.EditFiles(filenames)
 }
 
 func (editcmd *EditcmdClass) EditSecret(vaultName string, secretName string) (string, error) {
 
+	// A secret that does not exist is created, together with the vault if needed
 	secret, version, err := auroraconfig.GetSecret(vaultName, secretName, editcmd.Configuration)
-	if err != nil {
+	if err == auroraconfig.ErrSecretNotFound {
+		secret, version = "", ""
+	} else if err != nil {
 		return "", err
 	}
+	if fileutil.IsBinary([]byte(secret)) {
+		return "", errors.New("Error: Secret " + secretName + " is binary and cannot be edited")
+	}
 
 	var modifiedSecret = secret
 	modifiedSecret, err = editString(modifiedSecret)
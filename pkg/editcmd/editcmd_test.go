@@ -0,0 +1,123 @@
+package editcmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/cmdoptions"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/openshift"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestApplyFilesBuffer(t *testing.T) {
+	auroraConfig := serverapi.AuroraConfig{
+		Files: map[string]json.RawMessage{
+			"about.json":     json.RawMessage(`{"cluster": "utv"}`),
+			"api.json":       json.RawMessage(`{"version": "1"}`),
+			"utv/api.json":   json.RawMessage(`{}`),
+			"utv/about.json": json.RawMessage(`{}`),
+		},
+	}
+	filenames := []string{"api.json", "utv/api.json"}
+	buffer := files2Buffer(filenames, auroraConfig)
+
+	edited := strings.Replace(buffer, `"1"`, `"2"`, 1)
+	changed, problems := applyFilesBuffer(edited, &auroraConfig, filenames)
+	if len(problems) > 0 || len(changed) != 1 || changed[0] != "api.json" {
+		t.Errorf("applyFilesBuffer returned unexpected result: %v %v", changed, problems)
+	}
+	if !sameJson(string(auroraConfig.Files["api.json"]), `{"version": "2"}`) {
+		t.Errorf("applyFilesBuffer did not change api.json: %v", string(auroraConfig.Files["api.json"]))
+	}
+
+	_, problems = applyFilesBuffer(buffer+"--- about.json\n{}\n", &auroraConfig, filenames)
+	if len(problems) != 1 {
+		t.Errorf("applyFilesBuffer accepted a file that was not opened: %v", problems)
+	}
+	_, problems = applyFilesBuffer(strings.Replace(buffer, "{}", "{", 1), &auroraConfig, filenames)
+	if len(problems) != 1 {
+		t.Errorf("applyFilesBuffer accepted illegal JSON: %v", problems)
+	}
+}
+
+func TestAnnotateContent(t *testing.T) {
+	content := `{
+  "version": "1",
+  "config": {
+    "JAVA_OPTS": "-Xmx"
+  }
+}`
+	var itemError serverapi.ResponseItemError
+	json.Unmarshal([]byte(`{"application": "api", "environment": "utv", "messages": [
+		{"message": "Illegal version", "field": {"path": "/version", "value": "1", "source": "api.json"}},
+		{"message": "Illegal option", "field": {"path": "config/JAVA_OPTS", "value": "-Xmx", "source": "api.json"}},
+		{"message": "Unknown cluster", "field": {"path": "/cluster", "value": "x", "source": "about.json"}}
+	]}`), &itemError)
+	validationError := &auroraconfig.ValidationError{ItemErrors: []serverapi.ResponseItemError{itemError, itemError}}
+
+	expected := `# about.json: /cluster (x): Unknown cluster
+{
+  # Illegal version (1)
+  "version": "1",
+  "config": {
+    # Illegal option (-Xmx)
+    "JAVA_OPTS": "-Xmx"
+  }
+}`
+	annotated := annotateContent(content, "api.json", jsonFormat, "", validationError)
+	if annotated != expected {
+		t.Errorf("annotateContent returned unexpected content:\n%v", annotated)
+	}
+
+	buffer := "--- about.json\n{\n  \"cluster\": \"x\"\n}\n--- api.json\n" + content
+	annotated = annotateContent(buffer, "2 files", multiFileFormat, "", validationError)
+	if !strings.Contains(annotated, "--- about.json\n{\n  # Unknown cluster (x)\n  \"cluster\"") ||
+		!strings.Contains(annotated, "  # Illegal version (1)\n  \"version\"") || strings.HasPrefix(annotated, "#") {
+		t.Errorf("annotateContent returned unexpected buffer:\n%v", annotated)
+	}
+}
+
+func TestEditSecretCreatesMissingSecret(t *testing.T) {
+	defer gock.Off()
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	if err := ioutil.WriteFile(editor, []byte("#!/bin/sh\necho new > \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("EDITOR", editor)
+	defer os.Unsetenv("EDITOR")
+
+	boober := "http://boober.test"
+	gock.New(boober).
+		Get("/affiliation/paas/vault").
+		Reply(200).
+		JSON(map[string]interface{}{"success": true, "items": []interface{}{map[string]interface{}{"name": "other"}}})
+	gock.New(boober).
+		Put("/affiliation/paas/vault/myvault/secret/mysecret").
+		MatchHeader("AuroraConfigFileVersion", "^$").
+		BodyString(base64.StdEncoding.EncodeToString([]byte("new\n"))).
+		Reply(200).
+		JSON(map[string]interface{}{"success": true, "items": []interface{}{}})
+
+	editcmdObject := EditcmdClass{Configuration: &configuration.ConfigurationClass{
+		PersistentOptions: &cmdoptions.CommonCommandOptions{},
+		OpenshiftConfig: &openshift.OpenshiftConfig{
+			APICluster:  "utv",
+			Affiliation: "paas",
+			Clusters:    []*openshift.OpenshiftCluster{{Name: "utv", BooberUrl: boober, Reachable: true}},
+		},
+	}}
+	if _, err := editcmdObject.EditSecret("myvault", "mysecret"); err != nil {
+		t.Fatalf("EditSecret failed for a missing secret: %v", err)
+	}
+	if !gock.IsDone() {
+		t.Errorf("EditSecret did not create the secret")
+	}
+}
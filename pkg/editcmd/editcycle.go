@@ -7,14 +7,29 @@ import (
 	"github.com/skatteetaten/ao/pkg/configuration"
 	"github.com/skatteetaten/ao/pkg/fileutil"
 	"github.com/skatteetaten/ao/pkg/jsonutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
 )
 
 type storeFunc func(string, string, string, *configuration.ConfigurationClass) (string, error)
 
-func editCycle(content string, contentName string, version string, store storeFunc, configuration *configuration.ConfigurationClass) (modifiedContent string, output string, err error) {
+// The format of the edited content: how it is checked, how the comments added by the edit cycle are removed,
+// and how validation messages are placed by the field they refer to.  Without placeMessages, all
+// the messages are put at the top.
+type editFormat struct {
+	name          string
+	isLegal       func(string) bool
+	stripComments func(string) string
+	placeMessages func(content string, contentName string, messages []serverapi.ResponseItemMessage) (string, []bool)
+}
+
+var jsonFormat = editFormat{name: "JSON", isLegal: jsonutil.IsLegalJson, stripComments: stripComments, placeMessages: placeJsonMessages}
+
+// The content is opened in the editor until it is stored, or the user makes no changes.  If the store function
+// returns InvalidConfigurationError, the validation messages are added as comments and the content is reopened.
+func editCycle(content string, contentName string, version string, store storeFunc, format editFormat, configuration *configuration.ConfigurationClass) (modifiedContent string, output string, err error) {
 
 	var editCycleDone bool
-	content = jsonutil.PrettyPrintJson(content)
+	strip := format.stripComments
 	modifiedContent = content
 
 	for editCycleDone == false {
@@ -23,9 +38,9 @@ func editCycle(content string, contentName string, version string, store storeFu
 		if err != nil {
 			return "", "", err
 		}
-		if (stripComments(modifiedContent) == stripComments(contentBeforeEdit)) || stripComments(modifiedContent) == stripComments(content) {
-			if stripComments(modifiedContent) != stripComments(content) {
-				tempfile, err := fileutil.CreateTempFile(stripComments(modifiedContent))
+		if (strip(modifiedContent) == strip(contentBeforeEdit)) || strip(modifiedContent) == strip(content) {
+			if strip(modifiedContent) != strip(content) {
+				tempfile, err := fileutil.CreateTempFile(strip(modifiedContent))
 				if err != nil {
 					return "", "", nil
 				}
@@ -36,25 +51,22 @@ func editCycle(content string, contentName string, version string, store storeFu
 				fmt.Println("DEBUG: Content of modified file:")
 				fmt.Println(modifiedContent)
 				fmt.Println("DEBUG: Content of modified file stripped:")
-				fmt.Println(stripComments(modifiedContent))
+				fmt.Println(strip(modifiedContent))
 			}
-			return output, "", nil
+			return "", output, nil
 		}
-		modifiedContent = stripComments(modifiedContent)
-
-		if jsonutil.IsLegalJson(modifiedContent) {
-			validationMessages, err := store(contentName, modifiedContent, version, configuration)
-			if err != nil {
-				if err.Error() == auroraconfig.InvalidConfigurationError {
-					modifiedContent, _ = addComments(modifiedContent, validationMessages)
-				} else {
-					editCycleDone = true
-				}
+		modifiedContent = strip(modifiedContent)
+
+		if format.isLegal(modifiedContent) {
+			validationMessages, storeErr := store(contentName, modifiedContent, version, configuration)
+			if storeErr != nil && storeErr.Error() == auroraconfig.InvalidConfigurationError {
+				modifiedContent = annotateContent(modifiedContent, contentName, format, validationMessages, storeErr)
 			} else {
 				editCycleDone = true
+				err = storeErr
 			}
 		} else {
-			modifiedContent, _ = addComments(modifiedContent, "Illegal JSON Format")
+			modifiedContent, _ = addComments(modifiedContent, "Illegal "+format.name+" Format")
 		}
 	}
 
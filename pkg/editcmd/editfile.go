@@ -2,6 +2,7 @@ package editcmd
 
 import (
 	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/jsonutil"
 )
 
 func (editcmd *EditcmdClass) EditFile(filename string) (output string, err error) {
@@ -14,7 +15,7 @@ func (editcmd *EditcmdClass) EditFile(filename string) (output string, err error
 		return "", err
 	}
 
-	_, output, err = editCycle(content, filename, version, auroraconfig.PutFile, editcmd.Configuration)
+	_, output, err = editCycle(jsonutil.PrettyPrintJson(content), filename, version, auroraconfig.PutFile, jsonFormat, editcmd.Configuration)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,192 @@
+package editcmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/jsonutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+/*
+Several files are edited in one buffer, each file starting with a separator line:
+
+	--- about.json
+	{
+	  ...
+	}
+	--- utv/api.json
+	{
+	  ...
+	}
+
+The files are validated together and saved in one AuroraConfig write.  A file that is removed from the buffer
+is left unchanged.
+*/
+
+const fileSeparator = "--- "
+
+var multiFileFormat = editFormat{name: "multi-file", isLegal: isLegalFilesBuffer, stripComments: stripComments, placeMessages: placeFilesMessages}
+
+func files2Buffer(filenames []string, auroraConfig serverapi.AuroraConfig) (content string) {
+	for _, filename := range filenames {
+		content += fileSeparator + filename + "\n" + jsonutil.PrettyPrintJson(string(auroraConfig.Files[filename])) + "\n"
+	}
+	return content
+}
+
+// Splits the buffer into files, in the order they are found
+func buffer2Files(content string) (filenames []string, files map[string]string, err error) {
+	files = make(map[string]string)
+	var filename string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, fileSeparator) {
+			filename = strings.TrimSpace(strings.TrimPrefix(line, fileSeparator))
+			if _, found := files[filename]; found {
+				return nil, nil, errors.New("File " + filename + " is found twice")
+			}
+			filenames = append(filenames, filename)
+			files[filename] = ""
+			continue
+		}
+		if filename == "" {
+			if strings.TrimSpace(line) != "" {
+				return nil, nil, errors.New("Content before the first " + strings.TrimSpace(fileSeparator) + " <filename> line")
+			}
+			continue
+		}
+		files[filename] += line + "\n"
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return filenames, files, nil
+}
+
+func isLegalFilesBuffer(content string) bool {
+	_, _, err := buffer2Files(content)
+	return err == nil
+}
+
+// Places the messages in the file they refer to
+func placeFilesMessages(content string, contentName string, messages []serverapi.ResponseItemMessage) (annotated string, placed []bool) {
+	placed = make([]bool, len(messages))
+	var output []string
+	var filename string
+	var section []string
+	flush := func() {
+		if filename == "" {
+			output = append(output, section...)
+		} else {
+			sectionContent, sectionPlaced := placeJsonMessages(strings.Join(section, "\n"), filename, messages)
+			output = append(output, strings.Split(sectionContent, "\n")...)
+			for i := range placed {
+				placed[i] = placed[i] || sectionPlaced[i]
+			}
+		}
+		section = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, fileSeparator) {
+			flush()
+			output = append(output, line)
+			filename = strings.TrimSpace(strings.TrimPrefix(line, fileSeparator))
+			continue
+		}
+		section = append(section, line)
+	}
+	flush()
+	return strings.Join(output, "\n"), placed
+}
+
+func sameJson(json1 string, json2 string) bool {
+	var compact1, compact2 bytes.Buffer
+	if json.Compact(&compact1, []byte(json1)) != nil || json.Compact(&compact2, []byte(json2)) != nil {
+		return false
+	}
+	return compact1.String() == compact2.String()
+}
+
+// Applies the edited files to the AuroraConfig.  Returns the changed files, or the problems found as validation messages.
+func applyFilesBuffer(content string, auroraConfig *serverapi.AuroraConfig, editedFiles []string) (changed []string, problems []string) {
+	filenames, files, err := buffer2Files(content)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	edited := make(map[string]bool)
+	for _, filename := range editedFiles {
+		edited[filename] = true
+	}
+
+	for _, filename := range filenames {
+		if !edited[filename] {
+			problems = append(problems, "File "+filename+" was not opened for edit")
+			continue
+		}
+		if !jsonutil.IsLegalJson(files[filename]) {
+			problems = append(problems, "Illegal JSON Format in "+filename)
+			continue
+		}
+		if sameJson(files[filename], string(auroraConfig.Files[filename])) {
+			continue
+		}
+		auroraConfig.Files[filename] = json.RawMessage(files[filename])
+		changed = append(changed, filename)
+	}
+	return changed, problems
+}
+
+// Opens the files in one buffer, and saves the changes in one AuroraConfig write
+func (editcmd *EditcmdClass) EditFiles(filenames []string) (output string, err error) {
+	auroraConfig, err := auroraconfig.GetAuroraConfig(editcmd.Configuration)
+	if err != nil {
+		return "", err
+	}
+	for _, filename := range filenames {
+		if _, found := auroraConfig.Files[filename]; !found {
+			return "", errors.New("No such file: " + filename)
+		}
+	}
+
+	var changed []string
+	store := func(name string, content string, version string, configuration *configuration.ConfigurationClass) (string, error) {
+		modified := auroraConfig
+		modified.Files = make(map[string]json.RawMessage)
+		for filename, fileContent := range auroraConfig.Files {
+			modified.Files[filename] = fileContent
+		}
+		var problems []string
+		changed, problems = applyFilesBuffer(content, &modified, filenames)
+		if len(problems) > 0 {
+			return strings.Join(problems, "\n"), errors.New(auroraconfig.InvalidConfigurationError)
+		}
+		if len(changed) == 0 {
+			return "", nil
+		}
+		return auroraconfig.PutAuroraConfigWithValidation(modified, configuration)
+	}
+
+	buffer := files2Buffer(filenames, auroraConfig)
+	contentName := strconv.Itoa(len(filenames)) + " files"
+	_, output, err = editCycle(buffer, contentName, "", store, multiFileFormat, editcmd.Configuration)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		if len(changed) == 0 {
+			output = "No changes"
+		} else {
+			output = "Saved " + strings.Join(changed, ", ")
+		}
+	}
+	return output, nil
+}
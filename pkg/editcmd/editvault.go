@@ -1,39 +1,151 @@
 package editcmd
 
 import (
-	"encoding/json"
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/skatteetaten/ao/pkg/auroraconfig"
 	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/fileutil"
 	"github.com/skatteetaten/ao/pkg/serverapi"
+	"gopkg.in/yaml.v2"
 )
 
-func (editcmd *EditcmdClass) EditVault(vaultname string) (output string, err error) {
-	vault, err := auroraconfig.GetVault(vaultname, editcmd.Configuration)
-	if err != nil {
-		return "", err
+/*
+The vault is edited as a YAML document with the text secrets decoded:
+
+	name: myvault
+	permissions:
+	  groups:
+	  - mygroup
+	  users: []
+	secrets:
+	  db.properties: |
+	    user=myuser
+	    password=secret
+	  keystore.jks: <binary secret, 2342 bytes, kept as is>
+
+Binary secrets are shown as a placeholder, and are kept as long as the placeholder is not changed.
+A secret is removed by removing it from the document.
+*/
+
+type vaultDocument struct {
+	Name        string `yaml:"name"`
+	Permissions struct {
+		Groups []string `yaml:"groups"`
+		Users  []string `yaml:"users"`
+	} `yaml:"permissions"`
+	Secrets map[string]string `yaml:"secrets"`
+}
+
+var yamlFormat = editFormat{name: "YAML", isLegal: isLegalVaultYaml, stripComments: stripTopLevelComments}
+
+func binaryPlaceholder(content []byte) string {
+	return "<binary secret, " + strconv.Itoa(len(content)) + " bytes, kept as is>"
+}
+
+func isLegalVaultYaml(content string) bool {
+	var document vaultDocument
+	return yaml.UnmarshalStrict([]byte(content), &document) == nil
+}
+
+// Only comments in the first column are removed, so that lines starting with # in a secret are kept
+func stripTopLevelComments(content string) (uncommentedContent string) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	var newline = ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			uncommentedContent += newline + line
+			newline = "\n"
+		}
+	}
+	return
+}
+
+func vault2Yaml(vault serverapi.Vault) (content string, err error) {
+	var document vaultDocument
+	document.Name = vault.Name
+	document.Permissions.Groups = vault.Permissions.Groups
+	document.Permissions.Users = vault.Permissions.Users
+	document.Secrets = make(map[string]string)
+	for secretName, secret64 := range vault.Secrets {
+		decodedSecret, err := base64.StdEncoding.DecodeString(secret64)
+		if err != nil {
+			return "", errors.New("Error: Secret " + secretName + " in vault " + vault.Name + " is not legal base64: " + err.Error())
+		}
+		if fileutil.IsBinary(decodedSecret) {
+			document.Secrets[secretName] = binaryPlaceholder(decodedSecret)
+		} else {
+			document.Secrets[secretName] = string(decodedSecret)
+		}
 	}
 
-	vaultString, err := json.Marshal(vault)
+	yamlContent, err := yaml.Marshal(document)
 	if err != nil {
 		return "", err
 	}
+	return string(yamlContent), nil
+}
 
-	_, output, err = editCycle(string(vaultString), vaultname, "", putVaultString, editcmd.Configuration)
+// Converts the edited document back to a vault.  Binary secrets with an unchanged placeholder keep the
+// content from the original vault.
+func yaml2Vault(content string, original serverapi.Vault) (vault serverapi.Vault, err error) {
+	var document vaultDocument
+	if err := yaml.UnmarshalStrict([]byte(content), &document); err != nil {
+		return vault, err
+	}
+	if document.Name != original.Name {
+		return vault, errors.New("The vault name cannot be changed, use ao vault mv to rename the vault")
+	}
 
-	return output, nil
+	vault.Name = original.Name
+	vault.Permissions.Groups = document.Permissions.Groups
+	vault.Permissions.Users = document.Permissions.Users
+	vault.Versions = original.Versions
+	vault.Secrets = make(map[string]string)
+	for secretName, secret := range document.Secrets {
+		if originalSecret64, found := original.Secrets[secretName]; found {
+			decodedSecret, err := base64.StdEncoding.DecodeString(originalSecret64)
+			if err == nil && fileutil.IsBinary(decodedSecret) && secret == binaryPlaceholder(decodedSecret) {
+				vault.Secrets[secretName] = originalSecret64
+				continue
+			}
+		}
+		vault.Secrets[secretName] = base64.StdEncoding.EncodeToString([]byte(secret))
+	}
+	return vault, nil
 }
 
-func putVaultString(vaultname string, vaultString string, version string, configuration *configuration.ConfigurationClass) (output string, err error) {
-	var vault serverapi.Vault
+func (editcmd *EditcmdClass) EditVault(vaultname string) (output string, err error) {
+	vault, err := auroraconfig.GetVault(vaultname, editcmd.Configuration)
+	if err != nil {
+		return "", err
+	}
 
-	err = json.Unmarshal([]byte(vaultString), &vault)
+	content, err := vault2Yaml(vault)
 	if err != nil {
 		return "", err
 	}
-	output, err = auroraconfig.PutVault(vaultname, vault, version, configuration)
+
+	_, output, err = editCycle(content, vaultname, "", putVaultYaml(vault), yamlFormat, editcmd.Configuration)
 	if err != nil {
 		return "", err
 	}
-	return output, err
+	return output, nil
+}
+
+// Returns a store function for the edited document.  Errors in the document are returned as validation
+// messages, so that the document is reopened.
+func putVaultYaml(original serverapi.Vault) storeFunc {
+	return func(vaultname string, content string, version string, configuration *configuration.ConfigurationClass) (output string, err error) {
+		vault, err := yaml2Vault(content, original)
+		if err != nil {
+			return err.Error(), errors.New(auroraconfig.InvalidConfigurationError)
+		}
+		return auroraconfig.PutVault(vaultname, vault, version, configuration)
+	}
 }
@@ -0,0 +1,48 @@
+package editcmd
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+func TestVaultYaml(t *testing.T) {
+	binary := []byte{0xfe, 0xed, 0xfe, 0xed, 0x00, 0x02}
+	var vault serverapi.Vault
+	vault.Name = "myvault"
+	vault.Permissions.Groups = []string{"mygroup"}
+	vault.Secrets = map[string]string{
+		"db.properties": base64.StdEncoding.EncodeToString([]byte("# database\nuser=myuser\npassword=secret\n")),
+		"keystore.jks":  base64.StdEncoding.EncodeToString(binary),
+	}
+
+	content, err := vault2Yaml(vault)
+	if err != nil {
+		t.Fatalf("Error in vault2Yaml: %v", err.Error())
+	}
+	if !strings.Contains(content, "    password=secret\n") || !strings.Contains(content, binaryPlaceholder(binary)) {
+		t.Errorf("vault2Yaml returned unexpected content:\n%v", content)
+	}
+
+	edited := stripTopLevelComments("# Name: myvault\n" + strings.Replace(content, "password=secret", "password=changed", 1))
+	modified, err := yaml2Vault(edited, vault)
+	if err != nil {
+		t.Fatalf("Error in yaml2Vault: %v", err.Error())
+	}
+	expected := base64.StdEncoding.EncodeToString([]byte("# database\nuser=myuser\npassword=changed\n"))
+	if modified.Secrets["db.properties"] != expected {
+		t.Errorf("yaml2Vault returned unexpected secret: %v", modified.Secrets["db.properties"])
+	}
+	if modified.Secrets["keystore.jks"] != vault.Secrets["keystore.jks"] {
+		t.Errorf("yaml2Vault changed the binary secret")
+	}
+	if len(modified.Permissions.Groups) != 1 || modified.Permissions.Groups[0] != "mygroup" {
+		t.Errorf("yaml2Vault returned unexpected permissions: %v", modified.Permissions)
+	}
+
+	if _, err := yaml2Vault(strings.Replace(content, "name: myvault", "name: other", 1), vault); err == nil {
+		t.Errorf("yaml2Vault accepted a new vault name")
+	}
+}
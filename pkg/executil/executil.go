@@ -67,3 +67,38 @@ func PromptYNC(promptString string) (retval string, err error) {
 	}
 	return
 }
+
+// Returns true if both stdin and stdout are terminals, so that the user can be prompted
+func IsInteractive() bool {
+	for _, file := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := file.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Prompt for one of the choices by number.  An empty answer cancels.
+func PromptChoice(promptString string, choices []string) (choice string, err error) {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Println(promptString)
+	for i, choice := range choices {
+		fmt.Printf("  %d) %s\n", i+1, choice)
+	}
+	for {
+		fmt.Printf("Select 1-%d, or press enter to cancel: ", len(choices))
+		result, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		result = strings.TrimSpace(result)
+		if result == "" {
+			return "", errors.New("Operation cancelled by user")
+		}
+		var index int
+		if _, err := fmt.Sscanf(result, "%d", &index); err == nil && index >= 1 && index <= len(choices) {
+			return choices[index-1], nil
+		}
+	}
+}
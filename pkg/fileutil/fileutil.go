@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"unicode/utf8"
 )
 
 const SpecIllegal = -1
@@ -130,6 +131,37 @@ func WriteFile(folder string, filename string, content string) (err error) {
 	return
 }
 
+// Writes the content to a file that only the owner can read, also if the file exists
+func WritePrivateFile(filename string, content []byte) (err error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Returns true if the content is not text: it contains NUL bytes, is not legal UTF-8,
+// or has control characters other than whitespace
+func IsBinary(content []byte) bool {
+	if !utf8.Valid(content) {
+		return true
+	}
+	for _, b := range content {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return true
+		}
+	}
+	return false
+}
+
 func repeatString(str string, n int) (output string) {
 	for i := 0; i < n; i++ {
 		output += str
@@ -39,3 +39,18 @@ func TestIsLegalFileFolder(t *testing.T) {
 		t.Error("Failed to recognize illegal folder")
 	}
 }
+
+func TestIsBinary(t *testing.T) {
+	texts := []string{"", "user=test\npassword=secret\n", "æøå\r\n\ttabbed"}
+	for _, text := range texts {
+		if IsBinary([]byte(text)) {
+			t.Errorf("IsBinary returned true for %q", text)
+		}
+	}
+	binaries := [][]byte{{0xfe, 0xed, 0xfe, 0xed, 0x00, 0x00, 0x00, 0x02}, []byte("text\x00with nul"), {0xff, 0xfe, 'a'}}
+	for _, binary := range binaries {
+		if !IsBinary(binary) {
+			t.Errorf("IsBinary returned false for %q", binary)
+		}
+	}
+}
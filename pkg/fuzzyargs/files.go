@@ -0,0 +1,67 @@
+package fuzzyargs
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+func isFilePattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+func (fuzzyArgs *FuzzyArgs) addFile(filename string) {
+	for i := range fuzzyArgs.fileList {
+		if fuzzyArgs.fileList[i] == filename {
+			return
+		}
+	}
+	fuzzyArgs.fileList = append(fuzzyArgs.fileList, filename)
+}
+
+// Parse args that each describe one file, or a glob pattern that matches a set of files, like */api.json.
+// A pattern without the .json suffix matches the file name without the suffix.
+// Two args without a slash are first tried as <env> <file>, as for PopulateFuzzyFile.
+func (fuzzyArgs *FuzzyArgs) PopulateFuzzyFiles(args []string) (err error) {
+	fuzzyArgs.fileList = nil
+
+	if len(args) == 2 && !strings.Contains(args[0]+args[1], "/") && !isFilePattern(args[0]) && !isFilePattern(args[1]) {
+		fuzzyArgs.filename = ""
+		if err := fuzzyArgs.PopulateFuzzyFile(args); err == nil && fuzzyArgs.filename != "" {
+			fuzzyArgs.addFile(fuzzyArgs.filename)
+			return nil
+		}
+	}
+
+	for _, arg := range args {
+		if isFilePattern(arg) {
+			var found bool
+			for _, filename := range fuzzyArgs.legalFileList {
+				if globMatch(arg, filename) || globMatch(arg, strings.TrimSuffix(filename, ".json")) {
+					fuzzyArgs.addFile(filename)
+					found = true
+				}
+			}
+			if !found {
+				return errors.New("No files match " + arg)
+			}
+			continue
+		}
+
+		fuzzyArgs.filename = ""
+		if err := fuzzyArgs.PopulateFuzzyFile([]string{arg}); err != nil {
+			return err
+		}
+		if fuzzyArgs.filename == "" {
+			return errors.New("No matching file found for " + arg)
+		}
+		fuzzyArgs.addFile(fuzzyArgs.filename)
+	}
+
+	sort.Strings(fuzzyArgs.fileList)
+	return nil
+}
+
+func (fuzzyArgs *FuzzyArgs) GetFiles() []string {
+	return fuzzyArgs.fileList
+}
@@ -38,16 +38,20 @@ import (
 
 	"github.com/skatteetaten/ao/pkg/configuration"
 	"github.com/skatteetaten/ao/pkg/printutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
 )
 
 type FuzzyArgs struct {
-	configuration *configuration.ConfigurationClass
-	appList       []string
-	envList       []string
-	filename      string
-	legalAppList  []string
-	legalEnvList  []string
-	legalFileList []string
+	configuration  *configuration.ConfigurationClass
+	auroraConfig   serverapi.AuroraConfig
+	appList        []string
+	envList        []string
+	deploymentList []string
+	filename       string
+	fileList       []string
+	legalAppList   []string
+	legalEnvList   []string
+	legalFileList  []string
 }
 
 // ** Initialize **
@@ -60,51 +64,47 @@ func (fuzzyArgs *FuzzyArgs) Init(configuration *configuration.ConfigurationClass
 	return
 }
 
-// Try to match an argument with an app, returns "" if none found
+// Initialize with a given AuroraConfig instead of the one in Boober, like a locally checked out AuroraConfig
+func (fuzzyArgs *FuzzyArgs) InitWithAuroraConfig(configuration *configuration.ConfigurationClass, auroraConfig serverapi.AuroraConfig) {
+	fuzzyArgs.configuration = configuration
+	fuzzyArgs.addLegalFromAuroraConfig(auroraConfig)
+}
+
+// Try to match an argument with an app, returns "" if none found.
+// An exact match is preferred, then a prefix, then a part of the name, then the letters in order, then a name with a typing error.
 func (fuzzyArgs *FuzzyArgs) GetFuzzyApp(arg string) (app string, err error) {
 	if strings.HasSuffix(arg, ".json") {
 		arg = strings.TrimSuffix(arg, ".json")
 	}
-	// First check for exact match
-	for i := range fuzzyArgs.legalAppList {
-		if fuzzyArgs.legalAppList[i] == arg {
-			return arg, nil
-		}
-	}
-	// No exact match found, look for an app name that contains the string
-	for i := range fuzzyArgs.legalAppList {
-		if strings.Contains(fuzzyArgs.legalAppList[i], arg) {
-			if app != "" {
-				err = errors.New(arg + ": Not a unique application identifier, matching " + app + " and " + fuzzyArgs.legalAppList[i])
-				return "", err
-			}
-			app = fuzzyArgs.legalAppList[i]
-		}
-	}
-
-	return app, nil
+	matches, _ := rankMatches(arg, fuzzyArgs.legalAppList)
+	return disambiguate(arg, "application", matches)
 }
 
-// Try to match an argument with an env, returns "" if none found
+// Try to match an argument with an env, returns "" if none found.  The matches are ranked as for apps.
 func (fuzzyArgs *FuzzyArgs) GetFuzzyEnv(arg string) (env string, err error) {
-	// First check for exact match
-	for i := range fuzzyArgs.legalEnvList {
-		if fuzzyArgs.legalEnvList[i] == arg {
-			return arg, nil
-		}
-	}
-	// No exact match found, look for an env name that contains the string
-	for i := range fuzzyArgs.legalEnvList {
-		if strings.Contains(fuzzyArgs.legalEnvList[i], arg) {
-			if env != "" {
-				err = errors.New(arg + ": Not a unique environment identifier, matching both " + env + " and " + fuzzyArgs.legalEnvList[i])
-				return "", err
-			}
-			env = fuzzyArgs.legalEnvList[i]
-		}
+	matches, _ := rankMatches(arg, fuzzyArgs.legalEnvList)
+	return disambiguate(arg, "environment", matches)
+}
+
+// Try to match an argument that may be either an env or an app.  If both match, the better match is used.
+func (fuzzyArgs *FuzzyArgs) getFuzzyEnvOrApp(arg string) (env string, app string, err error) {
+	envMatches, envTier := rankMatches(arg, fuzzyArgs.legalEnvList)
+	appMatches, appTier := rankMatches(strings.TrimSuffix(arg, ".json"), fuzzyArgs.legalAppList)
+	if envTier < appTier {
+		appMatches = nil
+	} else if appTier < envTier {
+		envMatches = nil
+	} else if len(envMatches) > 0 && len(appMatches) > 0 {
+		return "", "", errors.New(arg + " matching both environment " + envMatches[0] + " and application " + appMatches[0])
 	}
 
-	return env, nil
+	if env, err = disambiguate(arg, "environment", envMatches); err != nil {
+		return "", "", err
+	}
+	if app, err = disambiguate(arg, "application", appMatches); err != nil {
+		return "", "", err
+	}
+	return env, app, nil
 }
 
 func (fuzzyArgs *FuzzyArgs) getLegalEnvAppFileList() (err error) {
@@ -113,6 +113,12 @@ func (fuzzyArgs *FuzzyArgs) getLegalEnvAppFileList() (err error) {
 	if err != nil {
 		return err
 	}
+	fuzzyArgs.addLegalFromAuroraConfig(auroraConfig)
+	return
+}
+
+func (fuzzyArgs *FuzzyArgs) addLegalFromAuroraConfig(auroraConfig serverapi.AuroraConfig) {
+	fuzzyArgs.auroraConfig = auroraConfig
 	for filename := range auroraConfig.Files {
 		fuzzyArgs.addLegalFile(filename)
 		if strings.Contains(filename, "/") {
@@ -127,8 +133,6 @@ func (fuzzyArgs *FuzzyArgs) getLegalEnvAppFileList() (err error) {
 			}
 		}
 	}
-
-	return
 }
 
 // Parse args, expect one or two args that describes a file
@@ -244,11 +248,11 @@ func (fuzzyArgs *FuzzyArgs) PopulateFuzzyEnvAppList(args []string, slashArg bool
 		} else {
 			if slashArg {
 				// Now we know that arg0 is and env and arg 1 is an app
-				env, err = fuzzyArgs.GetFuzzyEnv(args[i])
-				if err != nil {
-					return err
-				}
 				if i == 0 {
+					env, err = fuzzyArgs.GetFuzzyEnv(args[i])
+					if err != nil {
+						return err
+					}
 					if env == "" {
 						err = errors.New(args[i] + " does not match any environemt")
 						return err
@@ -269,19 +273,10 @@ func (fuzzyArgs *FuzzyArgs) PopulateFuzzyEnvAppList(args []string, slashArg bool
 				}
 			} else {
 				// We have a single spec that is either an app or an env
-				env, err = fuzzyArgs.GetFuzzyEnv(args[i])
-				if err != nil {
-					return err
-				}
-				app, err = fuzzyArgs.GetFuzzyApp(args[i])
+				env, app, err = fuzzyArgs.getFuzzyEnvOrApp(args[i])
 				if err != nil {
 					return err
 				}
-
-				if env != "" && app != "" {
-					err = errors.New(args[i] + " matching both environment " + env + " and application " + app)
-					return err
-				}
 				if env == "" && app == "" {
 					err = errors.New(args[i] + " matching neither an environment nor an application")
 					return err
@@ -289,36 +284,11 @@ func (fuzzyArgs *FuzzyArgs) PopulateFuzzyEnvAppList(args []string, slashArg bool
 				if env != "" {
 					fuzzyArgs.AddEnv(env)
 				}
-			}
-
-		}
-
-	}
-
-	for i = range args {
-		if !strings.Contains(args[i], "/") {
-			if !slashArg {
-				{
-					// We have a single spec that is either an app or an env
-
-					app, err = fuzzyArgs.GetFuzzyApp(args[i])
-					if err != nil {
-						return err
-					}
-					if app != "" {
-						fuzzyArgs.AddApp(app)
-					} else {
-						env, err = fuzzyArgs.GetFuzzyEnv(args[i])
-						if err != nil {
-							return err
-						}
-						if env == "" {
-							err = errors.New(args[i] + " matches neither an environment nor an application")
-							return err
-						}
-					}
+				if app != "" {
+					fuzzyArgs.AddApp(app)
 				}
 			}
+
 		}
 
 	}
@@ -452,13 +422,26 @@ func (fuzzyArgs *FuzzyArgs) addLegalEnv(env string) {
 }
 
 func (fuzzyArgs *FuzzyArgs) GetDeploymentSummaryString() (output string) {
-	output = "This will deploy " + strconv.Itoa(len(fuzzyArgs.GetApps())) + " applications in " + strconv.Itoa(len(fuzzyArgs.GetEnvs())) + " environments.\n"
-
 	var headers []string
 	headers = make([]string, 2)
 	headers[0] = "ENVIRONMENT"
 	headers[1] = "APPLICATION"
 
+	if fuzzyArgs.deploymentList != nil {
+		// The selection is a list of env/app pairs, so the columns are correlated
+		output = "This will deploy " + strconv.Itoa(len(fuzzyArgs.deploymentList)) + " applications in " + strconv.Itoa(len(fuzzyArgs.GetEnvs())) + " environments.\n"
+		var envs, apps []string
+		for _, deployment := range fuzzyArgs.deploymentList {
+			parts := strings.Split(deployment, "/")
+			envs = append(envs, parts[0])
+			apps = append(apps, parts[1])
+		}
+		output += printutil.FormatTable(headers, envs, apps)
+		return output
+	}
+
+	output = "This will deploy " + strconv.Itoa(len(fuzzyArgs.GetApps())) + " applications in " + strconv.Itoa(len(fuzzyArgs.GetEnvs())) + " environments.\n"
+
 	output += printutil.FormatTable(headers, fuzzyArgs.envList, fuzzyArgs.appList)
 
 	return output
@@ -0,0 +1,150 @@
+package fuzzyargs
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/executil"
+)
+
+// Match tiers, from the best to the weakest.  Only the candidates in the best tier that has any matches are used.
+const (
+	matchExact = iota
+	matchPrefix
+	matchContains
+	matchSubsequence
+	matchEditDistance
+	noMatch
+)
+
+// The number of candidates shown when a match is not unique
+const maxCandidates = 5
+
+// Replaced in the tests, so that they do not prompt when run on a terminal
+var isInteractive = executil.IsInteractive
+
+type rankedCandidate struct {
+	name     string
+	tier     int
+	distance int
+}
+
+func isSubsequence(arg string, candidate string) bool {
+	i := 0
+	for j := 0; j < len(candidate) && i < len(arg); j++ {
+		if candidate[j] == arg[i] {
+			i++
+		}
+	}
+	return i == len(arg)
+}
+
+func editDistance(s1 string, s2 string) int {
+	previous := make([]int, len(s2)+1)
+	current := make([]int, len(s2)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		current[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 1
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(s2)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Typing errors are accepted up to one per four characters, and not in names shorter than four characters
+func maxEditDistance(arg string) int {
+	if len(arg) < 4 {
+		return 0
+	}
+	if len(arg) < 8 {
+		return 1
+	}
+	return len(arg) / 4
+}
+
+func rankCandidate(arg string, candidate string) rankedCandidate {
+	ranked := rankedCandidate{name: candidate, tier: noMatch}
+	switch {
+	case candidate == arg:
+		ranked.tier = matchExact
+	case strings.HasPrefix(candidate, arg):
+		ranked.tier = matchPrefix
+	case strings.Contains(candidate, arg):
+		ranked.tier = matchContains
+	case isSubsequence(arg, candidate):
+		ranked.tier = matchSubsequence
+	default:
+		ranked.distance = editDistance(arg, candidate)
+		if ranked.distance <= maxEditDistance(arg) {
+			ranked.tier = matchEditDistance
+		}
+	}
+	return ranked
+}
+
+// Returns the candidates in the best matching tier, the closest first, and the tier
+func rankMatches(arg string, candidates []string) (matches []string, tier int) {
+	tier = noMatch
+	var best []rankedCandidate
+	for _, candidate := range candidates {
+		ranked := rankCandidate(arg, candidate)
+		if ranked.tier < tier {
+			tier = ranked.tier
+			best = nil
+		}
+		if ranked.tier == tier && tier != noMatch {
+			best = append(best, ranked)
+		}
+	}
+	sort.Slice(best, func(i, j int) bool {
+		if best[i].distance != best[j].distance {
+			return best[i].distance < best[j].distance
+		}
+		if len(best[i].name) != len(best[j].name) {
+			return len(best[i].name) < len(best[j].name)
+		}
+		return best[i].name < best[j].name
+	})
+	for _, ranked := range best {
+		matches = append(matches, ranked.name)
+	}
+	return matches, tier
+}
+
+// Picks one of several matches.  On a terminal the user is asked, otherwise an error lists the closest candidates.
+func disambiguate(arg string, kind string, matches []string) (match string, err error) {
+	if len(matches) == 0 {
+		return "", nil
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if isInteractive() {
+		return executil.PromptChoice(arg+" matches several "+kind+"s:", matches)
+	}
+	candidates := matches
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	message := arg + ": Not a unique " + kind + " identifier, matching " + strings.Join(candidates, ", ")
+	if len(matches) > maxCandidates {
+		message += " and more"
+	}
+	return "", errors.New(message)
+}
@@ -0,0 +1,36 @@
+package fuzzyargs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRankMatches(t *testing.T) {
+	candidates := []string{"api", "api-gateway", "web-api", "webapp", "referanse", "console"}
+
+	testCases := []struct {
+		arg      string
+		expected []string
+		tier     int
+	}{
+		{"api", []string{"api"}, matchExact},
+		{"api-", []string{"api-gateway"}, matchPrefix},
+		{"we", []string{"webapp", "web-api"}, matchPrefix},
+		{"pp", []string{"webapp"}, matchContains},
+		{"agw", []string{"api-gateway"}, matchSubsequence},
+		{"refernase", []string{"referanse"}, matchEditDistance},
+		{"xyz", nil, noMatch},
+	}
+	for _, tc := range testCases {
+		matches, tier := rankMatches(tc.arg, candidates)
+		if !reflect.DeepEqual(matches, tc.expected) || tier != tc.tier {
+			t.Errorf("rankMatches returned %v (tier %v) for %v, expected %v (tier %v)", matches, tier, tc.arg, tc.expected, tc.tier)
+		}
+	}
+
+	defer func(interactive func() bool) { isInteractive = interactive }(isInteractive)
+	isInteractive = func() bool { return false }
+	if _, err := disambiguate("we", "application", []string{"webapp", "web-api"}); err == nil {
+		t.Error("disambiguate accepted an ambiguous match when not interactive")
+	}
+}
@@ -0,0 +1,211 @@
+package fuzzyargs
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+)
+
+/*
+Selectors pick a set of env/app deployments, as opposed to the fuzzy arguments that pick a list of envs and a list of apps.
+
+Selector types:
+
+<glob>									Glob pattern, e.g. *-test/api-*.  Without a slash, the pattern
+										is matched against both the env and the app name
+~<regex>								Regular expression matched against env/app
+<field>=<glob>							Field in the merged configuration matches, e.g. cluster=utv or groups=frontend.
+										For list fields such as groups, it is enough that one element matches
+<field>!=<glob>							Field in the merged configuration does not match
+!<selector>								Exclude the deployments matching the selector
+*/
+
+type selector struct {
+	exclude bool
+	field   string
+	negate  bool
+	glob    string
+	regex   *regexp.Regexp
+}
+
+// Returns true if the argument is a selector rather than a fuzzy env/app argument
+func IsSelector(arg string) bool {
+	return strings.HasPrefix(arg, "!") || strings.HasPrefix(arg, "~") || strings.Contains(arg, "=") ||
+		strings.ContainsAny(arg, "*?[")
+}
+
+// Splits arguments into fuzzy arguments and selectors
+func SplitSelectors(args []string) (fuzzy []string, selectors []string) {
+	for _, arg := range args {
+		if IsSelector(arg) {
+			selectors = append(selectors, arg)
+		} else {
+			fuzzy = append(fuzzy, arg)
+		}
+	}
+	return fuzzy, selectors
+}
+
+func parseSelector(arg string) (sel selector, err error) {
+	if strings.HasPrefix(arg, "!") {
+		sel, err = parseSelector(arg[1:])
+		sel.exclude = true
+		return sel, err
+	}
+	if strings.HasPrefix(arg, "~") {
+		sel.regex, err = regexp.Compile(arg[1:])
+		if err != nil {
+			return sel, errors.New("Illegal regular expression " + arg + ": " + err.Error())
+		}
+		return sel, nil
+	}
+	if index := strings.Index(arg, "="); index > 0 {
+		sel.field = arg[:index]
+		sel.glob = arg[index+1:]
+		if strings.HasSuffix(sel.field, "!") {
+			sel.field = strings.TrimSuffix(sel.field, "!")
+			sel.negate = true
+		}
+	} else {
+		sel.glob = arg
+	}
+	if _, err := path.Match(sel.glob, ""); err != nil {
+		return sel, errors.New("Illegal pattern " + arg + ": " + err.Error())
+	}
+	return sel, nil
+}
+
+func (sel *selector) isFieldSelector() bool {
+	return sel.field != ""
+}
+
+func (sel *selector) matches(deployment string, spec map[string]interface{}) bool {
+	if sel.regex != nil {
+		return sel.regex.MatchString(deployment)
+	}
+
+	if sel.isFieldSelector() {
+		var matched bool
+		if values, isList := spec[sel.field].([]interface{}); isList {
+			for _, value := range values {
+				if globMatch(sel.glob, auroraconfig.SpecValue2String(value)) {
+					matched = true
+				}
+			}
+		} else {
+			matched = globMatch(sel.glob, auroraconfig.GetSpecField(spec, sel.field))
+		}
+		return matched != sel.negate
+	}
+
+	if strings.Contains(sel.glob, "/") {
+		return globMatch(sel.glob, deployment)
+	}
+	parts := strings.Split(deployment, "/")
+	return globMatch(sel.glob, parts[0]) || globMatch(sel.glob, parts[1])
+}
+
+func globMatch(pattern string, value string) bool {
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// Selects deployments from the AuroraConfig.
+// The candidates are the base deployments, or all deployments if there are none.  If there are glob or regex
+// selectors, only the candidates that match one of them are selected, so a fuzzy env/app narrows them down.
+// Field selectors then keep only the deployments where all the fields match, and exclusions remove deployments.
+func (fuzzyArgs *FuzzyArgs) SelectDeployments(base []string, selectorArgs []string) (deployments []string, err error) {
+	var includes, filters, excludes []selector
+	for _, arg := range selectorArgs {
+		sel, err := parseSelector(arg)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case sel.exclude:
+			excludes = append(excludes, sel)
+		case sel.isFieldSelector():
+			filters = append(filters, sel)
+		default:
+			includes = append(includes, sel)
+		}
+	}
+
+	if len(base) == 0 && len(includes) == 0 && len(filters) == 0 {
+		return nil, errors.New("Exclusions must be combined with a pattern, a field selector, an env/app or --all")
+	}
+
+	all := auroraconfig.GetDeployments(fuzzyArgs.auroraConfig)
+	specs := make(map[string]map[string]interface{})
+	for _, deployment := range all {
+		parts := strings.Split(deployment, "/")
+		specs[deployment], err = auroraconfig.GetDeploymentSpec(fuzzyArgs.auroraConfig, parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := all
+	if len(base) > 0 {
+		candidates = base
+	}
+	selected := make(map[string]bool)
+	for _, deployment := range candidates {
+		if _, found := specs[deployment]; !found {
+			continue
+		}
+		if len(includes) == 0 {
+			selected[deployment] = true
+		}
+		for i := range includes {
+			if includes[i].matches(deployment, specs[deployment]) {
+				selected[deployment] = true
+			}
+		}
+	}
+
+	for deployment := range selected {
+		for i := range filters {
+			if !filters[i].matches(deployment, specs[deployment]) {
+				delete(selected, deployment)
+				break
+			}
+		}
+	}
+
+	for deployment := range selected {
+		for i := range excludes {
+			if excludes[i].matches(deployment, specs[deployment]) {
+				delete(selected, deployment)
+				break
+			}
+		}
+	}
+
+	for deployment := range selected {
+		deployments = append(deployments, deployment)
+	}
+	sort.Strings(deployments)
+	return deployments, nil
+}
+
+// Sets the selected deployments, and the env and app lists to the envs and apps in the deployments
+func (fuzzyArgs *FuzzyArgs) SetDeployments(deployments []string) {
+	fuzzyArgs.deploymentList = deployments
+	fuzzyArgs.envList = nil
+	fuzzyArgs.appList = nil
+	for _, deployment := range deployments {
+		parts := strings.Split(deployment, "/")
+		fuzzyArgs.AddEnv(parts[0])
+		fuzzyArgs.AddApp(parts[1])
+	}
+}
+
+// Returns the selected env/app deployments, or nil if the selection is the combination of the env and app lists
+func (fuzzyArgs *FuzzyArgs) GetDeployments() []string {
+	return fuzzyArgs.deploymentList
+}
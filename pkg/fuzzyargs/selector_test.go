@@ -0,0 +1,56 @@
+package fuzzyargs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+func TestSelectDeployments(t *testing.T) {
+	var fuzzyArgs FuzzyArgs
+	fuzzyArgs.auroraConfig = serverapi.AuroraConfig{
+		Files: map[string]json.RawMessage{
+			"about.json":          json.RawMessage(`{"cluster": "utv"}`),
+			"api-a.json":          json.RawMessage(`{"groups": ["frontend"]}`),
+			"api-b.json":          json.RawMessage(`{}`),
+			"web.json":            json.RawMessage(`{"type": "development"}`),
+			"sat-test/about.json": json.RawMessage(`{}`),
+			"sat-test/api-a.json": json.RawMessage(`{}`),
+			"sat-test/api-b.json": json.RawMessage(`{}`),
+			"sat-test/web.json":   json.RawMessage(`{}`),
+			"sat-prod/about.json": json.RawMessage(`{"cluster": "prod"}`),
+			"sat-prod/api-a.json": json.RawMessage(`{}`),
+			"sat-prod/web.json":   json.RawMessage(`{}`),
+		},
+	}
+
+	testCases := []struct {
+		base      []string
+		selectors []string
+		expected  []string
+	}{
+		{nil, []string{"*-test/api-*"}, []string{"sat-test/api-a", "sat-test/api-b"}},
+		{nil, []string{"api-*", "!*-prod/*"}, []string{"sat-test/api-a", "sat-test/api-b"}},
+		{nil, []string{"cluster=prod"}, []string{"sat-prod/api-a", "sat-prod/web"}},
+		{nil, []string{"groups=front*"}, []string{"sat-prod/api-a", "sat-test/api-a"}},
+		{nil, []string{"~^sat-test/(web|api-b)$"}, []string{"sat-test/api-b", "sat-test/web"}},
+		{[]string{"sat-test/web", "sat-prod/web"}, []string{"cluster!=utv"}, []string{"sat-prod/web"}},
+		{[]string{"sat-test/api-a", "sat-test/api-b", "sat-test/web"}, []string{"api-*"}, []string{"sat-test/api-a", "sat-test/api-b"}},
+		{[]string{"sat-prod/api-a", "sat-prod/web"}, []string{"~/web$", "!cluster=utv"}, []string{"sat-prod/web"}},
+	}
+	for _, tc := range testCases {
+		deployments, err := fuzzyArgs.SelectDeployments(tc.base, tc.selectors)
+		if err != nil {
+			t.Errorf("SelectDeployments returned an error for %v: %v", tc.selectors, err)
+		}
+		if !reflect.DeepEqual(deployments, tc.expected) {
+			t.Errorf("SelectDeployments returned %v for %v, expected %v", deployments, tc.selectors, tc.expected)
+		}
+	}
+
+	if _, err := fuzzyArgs.SelectDeployments(nil, []string{"!*-prod/*"}); err == nil {
+		t.Error("SelectDeployments accepted a selection with only exclusions")
+	}
+}
@@ -1,7 +1,6 @@
 package getcmd
 
 import (
-	"encoding/base64"
 	"fmt"
 
 	"github.com/skatteetaten/ao/pkg/auroraconfig"
@@ -132,24 +131,6 @@ func (getcmd *GetcmdClass) Vault(vaultName string) (string, error) {
 	return output, nil
 }
 
-func (getcmd *GetcmdClass) Secret(vaultName string, secretName string) (string, error) {
-	var output string
-	var vaults []serverapi.Vault
-	vaults, err := auroraconfig.GetVaultsArray(getcmd.Configuration)
-
-	if err != nil {
-		return "", err
-	}
-
-	for vaultindex := range vaults {
-		if vaults[vaultindex].Name == vaultName {
-			decodedSecret, _ := base64.StdEncoding.DecodeString(vaults[vaultindex].Secrets[secretName])
-			output += string(decodedSecret)
-		}
-	}
-	return output, nil
-}
-
 func (getcmd *GetcmdClass) KubeConfig() (string, error) {
 	var kubeConfig kubernetes.KubeConfig
 
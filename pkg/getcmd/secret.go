@@ -0,0 +1,104 @@
+package getcmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/fileutil"
+)
+
+const maskedValue = "********"
+
+// The clipboard programs that are tried, in order
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+type SecretOptions struct {
+	Reveal     bool
+	OutputFile string
+	Clipboard  bool
+}
+
+// Shows a secret.  The values are masked unless reveal is set, and binary secrets are never written to
+// the terminal.  The secret may be written to a file that only the owner can read, or copied to the clipboard.
+func (getcmd *GetcmdClass) Secret(vaultName string, secretName string, options SecretOptions) (string, error) {
+	secret, _, err := auroraconfig.GetSecret(vaultName, secretName, getcmd.Configuration)
+	if err != nil {
+		return "", err
+	}
+	content := []byte(secret)
+	binary := fileutil.IsBinary(content)
+
+	if options.OutputFile != "" {
+		if err := fileutil.WritePrivateFile(options.OutputFile, content); err != nil {
+			return "", errors.New("Error: Unable to write " + options.OutputFile + ": " + err.Error())
+		}
+		return "Secret " + secretName + " (" + strconv.Itoa(len(content)) + " bytes) written to " + options.OutputFile, nil
+	}
+
+	if binary {
+		if options.Reveal || options.Clipboard {
+			return "", errors.New("Error: Secret " + secretName + " is binary (" + strconv.Itoa(len(content)) + " bytes), use --output-file to save it")
+		}
+		return secretName + ": binary, " + strconv.Itoa(len(content)) + " bytes\nUse --output-file to save it", nil
+	}
+
+	if options.Clipboard {
+		if err := copyToClipboard(content); err != nil {
+			return "", err
+		}
+		return "Secret " + secretName + " copied to the clipboard", nil
+	}
+
+	if options.Reveal {
+		return secret, nil
+	}
+	return maskSecret(secret) + "\nUse --reveal to show the values", nil
+}
+
+// Masks the values of a properties secret and keeps the keys and comments.  Lines that are not
+// properties are masked completely.
+func maskSecret(secret string) (masked string) {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(secret))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") {
+			lines = append(lines, line)
+			continue
+		}
+		if index := strings.IndexAny(line, "=:"); index > 0 {
+			lines = append(lines, line[:index+1]+maskedValue)
+		} else {
+			lines = append(lines, maskedValue)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func copyToClipboard(content []byte) error {
+	for _, command := range clipboardCommands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = bytes.NewReader(content)
+		if err := cmd.Run(); err != nil {
+			return errors.New("Error: Unable to copy to the clipboard with " + command[0] + ": " + err.Error())
+		}
+		return nil
+	}
+	return errors.New("Error: No clipboard program found, use --output-file instead")
+}
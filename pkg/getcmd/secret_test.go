@@ -0,0 +1,11 @@
+package getcmd
+
+import "testing"
+
+func TestMaskSecret(t *testing.T) {
+	secret := "# database\nuser=test\npassword: secret\n\nsomething else"
+	expected := "# database\nuser=********\npassword:********\n\n********"
+	if masked := maskSecret(secret); masked != expected {
+		t.Errorf("Unexpected masked secret, expected %q, got %q", expected, masked)
+	}
+}
@@ -0,0 +1,205 @@
+package hookscmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+)
+
+/*
+Git hooks for a checked out AuroraConfig.  The hooks call ao hooks run, which checks the JSON syntax of the changed
+files.  Nothing is sent to Boober, the files are validated there when they are saved with ao save.
+
+The pre-commit hook checks the staged files, and the pre-push hook checks the files changed in the pushed commits.
+The hooks are run by git, so the files are read with the git command, which also handles the temporary index used
+by git commit -a.
+*/
+
+const hookMarker = "# Installed by ao hooks install"
+
+var hookNames = []string{"pre-commit", "pre-push"}
+
+var ErrHookFailed = errors.New("The AuroraConfig has syntax errors, fix the errors above before committing or pushing")
+
+const zeroHash = "0000000000000000000000000000000000000000"
+
+type HookscmdClass struct {
+	Configuration *configuration.ConfigurationClass
+}
+
+func hookScript(hookName string) string {
+	return "#!/bin/sh\n" + hookMarker + "\nexec ao hooks run " + hookName + " \"$@\"\n"
+}
+
+func hooksFolder() (string, error) {
+	output, err := auroraconfig.GitCommand("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(strings.TrimSpace(output))
+}
+
+// Installs the hooks.  Hooks that are not installed by ao are kept, unless force is given, then they are moved to
+// <hook>.orig.
+func (hookscmd *HookscmdClass) Install(force bool) (output string, err error) {
+	folder, err := hooksFolder()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return "", err
+	}
+
+	for _, hookName := range hookNames {
+		path := filepath.Join(folder, hookName)
+		if existing, err := ioutil.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) {
+			if !force {
+				return output, errors.New("A " + hookName + " hook already exists in " + folder + ", use --force to replace it")
+			}
+			if err := os.Rename(path, path+".orig"); err != nil {
+				return output, err
+			}
+			output += "Moved the existing " + hookName + " hook to " + path + ".orig\n"
+		}
+		if err := ioutil.WriteFile(path, []byte(hookScript(hookName)), 0755); err != nil {
+			return output, err
+		}
+		output += "Installed " + path + "\n"
+	}
+	return output, nil
+}
+
+// Removes the hooks installed by ao
+func (hookscmd *HookscmdClass) Uninstall() (output string, err error) {
+	folder, err := hooksFolder()
+	if err != nil {
+		return "", err
+	}
+	for _, hookName := range hookNames {
+		path := filepath.Join(folder, hookName)
+		existing, err := ioutil.ReadFile(path)
+		if err != nil || !strings.Contains(string(existing), hookMarker) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return output, err
+		}
+		output += "Removed " + path + "\n"
+	}
+	return output, nil
+}
+
+// Parses the output of git diff --name-status --no-renames -z, which is the status and the path for each file.
+// Deleted files have nothing to check, so only the added and modified AuroraConfig files are returned.
+func parseNameStatus(diff string) (updated []string) {
+	entries := strings.Split(diff, "\x00")
+	for i := 0; i+1 < len(entries); i += 2 {
+		status, filename := entries[i], entries[i+1]
+		if auroraconfig.IsAuroraConfigFile(filename) && !strings.HasPrefix(status, "D") {
+			updated = append(updated, filename)
+		}
+	}
+	return updated
+}
+
+// Checks the staged files
+func (hookscmd *HookscmdClass) PreCommit() (output string, err error) {
+	diff, err := auroraconfig.GitCommand("diff", "--cached", "--name-status", "--no-renames", "-z")
+	if err != nil {
+		return "", err
+	}
+	updated := parseNameStatus(diff)
+
+	files := make(map[string]string)
+	for _, filename := range updated {
+		files[filename], err = auroraconfig.GitCommand("show", ":"+filename)
+		if err != nil {
+			return "", err
+		}
+	}
+	return checkFiles(files)
+}
+
+// Checks the files changed in the pushed commits.  Git gives one line for each ref that is pushed:
+// <local ref> <local sha1> <remote ref> <remote sha1>
+func (hookscmd *HookscmdClass) PrePush(refs io.Reader) (output string, err error) {
+	files := make(map[string]string)
+
+	scanner := bufio.NewScanner(refs)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] == zeroHash {
+			continue
+		}
+		localHash, remoteHash := fields[1], fields[3]
+
+		var updated []string
+		diff, diffErr := auroraconfig.GitCommand("diff", "--name-status", "--no-renames", "-z", remoteHash, localHash)
+		if remoteHash != zeroHash && diffErr == nil {
+			updated = parseNameStatus(diff)
+		} else {
+			// A new branch, or a remote commit that is not fetched, all the files are checked
+			tree, err := auroraconfig.GitCommand("ls-tree", "-r", "--name-only", "-z", localHash)
+			if err != nil {
+				return "", err
+			}
+			for _, filename := range strings.Split(tree, "\x00") {
+				if auroraconfig.IsAuroraConfigFile(filename) {
+					updated = append(updated, filename)
+				}
+			}
+		}
+
+		for _, filename := range updated {
+			files[filename], err = auroraconfig.GitCommand("show", localHash+":"+filename)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return checkFiles(files)
+}
+
+// Returns the syntax error in the JSON file, with the line it is found on
+func checkSyntax(filename string, content string) string {
+	var parsed interface{}
+	err := json.Unmarshal([]byte(content), &parsed)
+	if err == nil {
+		return ""
+	}
+	if syntaxError, ok := err.(*json.SyntaxError); ok {
+		line := strings.Count(content[:syntaxError.Offset], "\n") + 1
+		return filename + ":" + strconv.Itoa(line) + ": " + syntaxError.Error()
+	}
+	return filename + ": " + err.Error()
+}
+
+// Checks the syntax of the files, sorted by filename
+func checkFiles(files map[string]string) (output string, err error) {
+	var filenames []string
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var syntaxErrors []string
+	for _, filename := range filenames {
+		if message := checkSyntax(filename, files[filename]); message != "" {
+			syntaxErrors = append(syntaxErrors, message)
+		}
+	}
+	if len(syntaxErrors) > 0 {
+		return strings.Join(syntaxErrors, "\n") + "\n", ErrHookFailed
+	}
+	return "", nil
+}
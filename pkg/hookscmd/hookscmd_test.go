@@ -0,0 +1,28 @@
+package hookscmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	diff := "M\x00test/api.json\x00A\x00test/new.json\x00D\x00old.json\x00M\x00README.md\x00A\x00.ao/x.json\x00"
+
+	updated := parseNameStatus(diff)
+
+	if !reflect.DeepEqual(updated, []string{"test/api.json", "test/new.json"}) {
+		t.Errorf("Unexpected updated files: %v", updated)
+	}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	if message := checkSyntax("api.json", "{\n  \"replicas\": 2\n}"); message != "" {
+		t.Errorf("Expected no error, got %v", message)
+	}
+
+	message := checkSyntax("api.json", "{\n  \"replicas\": 2,\n  \"type\" \"deploy\"\n}")
+	if !strings.HasPrefix(message, "api.json:3: ") {
+		t.Errorf("Expected an error on line 3, got %v", message)
+	}
+}
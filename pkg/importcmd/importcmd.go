@@ -71,6 +71,7 @@ func (importObj *ImportClass) ExecuteImport(args []string, localDryRun bool) (
 		if localDryRun {
 			return fmt.Sprintf("%v", string(jsonutil.PrettyPrintJson(jsonStr))), nil
 		} else {
+			defer auroraconfig.InvalidateCache(importObj.Configuration)
 			responses, err = serverapi.CallApi(http.MethodPut, apiEndpoint, jsonStr, persistentOptions.ShowConfig,
 				persistentOptions.ShowObjects, true, persistentOptions.Localhost,
 				persistentOptions.Verbose, importObj.Configuration.OpenshiftConfig, persistentOptions.DryRun, persistentOptions.Debug, persistentOptions.ServerApi, persistentOptions.Token)
@@ -119,7 +120,7 @@ func generateJson(folder string, affiliation string, dryRun bool) (jsonStr strin
 		}
 	}
 
-	setupCommand.AuroraConfig.Files = returnMap
+	setupCommand.AuroraConfig = &jsonutil.AuroraConfigPayload{Files: returnMap}
 
 	var jsonByte []byte
 
@@ -0,0 +1,385 @@
+package jsonutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Support for RFC 6902 (JSON Patch) and RFC 7396 (JSON Merge Patch) documents.
+// The documents are decoded with UseNumber so that numbers survive a round trip unchanged.
+
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+func decodeJson(document []byte) (value interface{}, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+// Returns true if the given json is an array, which is how a RFC 6902 JSON Patch is recognized
+func IsJsonPatch(patch json.RawMessage) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(patch)), "[")
+}
+
+// Applies a patch to a document.  A JSON array is treated as a RFC 6902 JSON Patch,
+// anything else is treated as a RFC 7396 Merge Patch.
+func ApplyPatch(document json.RawMessage, patch json.RawMessage) (json.RawMessage, error) {
+	if IsJsonPatch(patch) {
+		return ApplyJsonPatch(document, patch)
+	}
+	return ApplyMergePatch(document, patch)
+}
+
+// Applies a RFC 7396 Merge Patch to a document
+func ApplyMergePatch(document json.RawMessage, patch json.RawMessage) (json.RawMessage, error) {
+	var target interface{}
+	var err error
+
+	if len(bytes.TrimSpace(document)) > 0 {
+		target, err = decodeJson(document)
+		if err != nil {
+			return nil, errors.New("Illegal JSON in document: " + err.Error())
+		}
+	}
+	patchValue, err := decodeJson(patch)
+	if err != nil {
+		return nil, errors.New("Illegal JSON in merge patch: " + err.Error())
+	}
+
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+		} else {
+			targetMap[key] = mergePatch(targetMap[key], value)
+		}
+	}
+	return targetMap
+}
+
+// Applies a RFC 6902 JSON Patch to a document
+func ApplyJsonPatch(document json.RawMessage, patch json.RawMessage) (json.RawMessage, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, errors.New("Illegal JSON Patch: " + err.Error())
+	}
+
+	doc, err := decodeJson(document)
+	if err != nil {
+		return nil, errors.New("Illegal JSON in document: " + err.Error())
+	}
+
+	for i, operation := range operations {
+		doc, err = applyJsonPatchOperation(doc, operation)
+		if err != nil {
+			return nil, fmt.Errorf("JSON Patch operation %d (%s %s) failed: %v", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func applyJsonPatchOperation(doc interface{}, operation jsonPatchOperation) (interface{}, error) {
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		value, err := decodeJson(*operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch operation.Op {
+		case "add":
+			return pointerAdd(doc, operation.Path, value)
+		case "replace":
+			if _, err := pointerGet(doc, operation.Path); err != nil {
+				return nil, err
+			}
+			doc, _, err = pointerRemove(doc, operation.Path)
+			if err != nil {
+				return nil, err
+			}
+			return pointerAdd(doc, operation.Path, value)
+		default:
+			current, err := pointerGet(doc, operation.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(current, value) {
+				return nil, errors.New("test failed")
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err := pointerRemove(doc, operation.Path)
+		return doc, err
+	case "move":
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, value, err := pointerRemove(doc, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, operation.Path, value)
+	case "copy":
+		value, err := pointerGet(doc, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, operation.Path, deepCopy(value))
+	}
+	return nil, errors.New("unknown operation")
+}
+
+func jsonEqual(a interface{}, b interface{}) bool {
+	aNumber, aIsNumber := a.(json.Number)
+	bNumber, bIsNumber := b.(json.Number)
+	if aIsNumber && bIsNumber {
+		aFloat, aErr := aNumber.Float64()
+		bFloat, bErr := bNumber.Float64()
+		return aErr == nil && bErr == nil && aFloat == bFloat
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			copied[k] = deepCopy(v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, v := range typed {
+			copied[i] = deepCopy(v)
+		}
+		return copied
+	}
+	return value
+}
+
+// Splits a RFC 6901 JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("illegal JSON Pointer " + pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(tokens[i], "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, errors.New("illegal array index " + token)
+	}
+	if index > length || (!allowEnd && index == length) {
+		return 0, errors.New("array index out of bounds " + token)
+	}
+	return index, nil
+}
+
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, token := range tokens {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			value, found := typed[token]
+			if !found {
+				return nil, errors.New("no such path " + pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(typed), false)
+			if err != nil {
+				return nil, err
+			}
+			current = typed[index]
+		default:
+			return nil, errors.New("no such path " + pointer)
+		}
+	}
+	return current, nil
+}
+
+// Adds a value at the pointer and returns the (possibly new) root
+func pointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return setChild(doc, tokens, value)
+}
+
+func setChild(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if last {
+			typed[token] = value
+			return typed, nil
+		}
+		child, found := typed[token]
+		if !found {
+			return nil, errors.New("no such path /" + strings.Join(tokens, "/"))
+		}
+		child, err := setChild(child, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		typed[token] = child
+		return typed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed), last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = value
+			return typed, nil
+		}
+		child, err := setChild(typed[index], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		typed[index] = child
+		return typed, nil
+	}
+	return nil, errors.New("no such path /" + strings.Join(tokens, "/"))
+}
+
+// Removes the value at the pointer and returns the new root along with the removed value
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	return removeChild(doc, tokens)
+}
+
+func removeChild(node interface{}, tokens []string) (interface{}, interface{}, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		child, found := typed[token]
+		if !found {
+			return nil, nil, errors.New("no such path /" + strings.Join(tokens, "/"))
+		}
+		if last {
+			delete(typed, token)
+			return typed, child, nil
+		}
+		newChild, removed, err := removeChild(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		typed[token] = newChild
+		return typed, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(typed), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := typed[index]
+			return append(typed[:index], typed[index+1:]...), removed, nil
+		}
+		newChild, removed, err := removeChild(typed[index], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		typed[index] = newChild
+		return typed, removed, nil
+	}
+	return nil, nil, errors.New("no such path /" + strings.Join(tokens, "/"))
+}
+
+// Creates a RFC 7396 Merge Patch that transforms the original document into the modified document
+func CreateMergePatch(original json.RawMessage, modified json.RawMessage) (json.RawMessage, error) {
+	originalValue, err := decodeJson(original)
+	if err != nil {
+		return nil, errors.New("Illegal JSON in original document: " + err.Error())
+	}
+	modifiedValue, err := decodeJson(modified)
+	if err != nil {
+		return nil, errors.New("Illegal JSON in modified document: " + err.Error())
+	}
+
+	return json.Marshal(mergePatchDiff(originalValue, modifiedValue))
+}
+
+func mergePatchDiff(original interface{}, modified interface{}) interface{} {
+	originalMap, originalIsMap := original.(map[string]interface{})
+	modifiedMap, modifiedIsMap := modified.(map[string]interface{})
+	if !originalIsMap || !modifiedIsMap {
+		return modified
+	}
+
+	diff := make(map[string]interface{})
+	for key := range originalMap {
+		if _, found := modifiedMap[key]; !found {
+			diff[key] = nil
+		}
+	}
+	for key, value := range modifiedMap {
+		originalValue, found := originalMap[key]
+		if !found {
+			diff[key] = value
+		} else if !jsonEqual(originalValue, value) {
+			diff[key] = mergePatchDiff(originalValue, value)
+		}
+	}
+	return diff
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/skatteetaten/ao/pkg/cryptutil"
 	"github.com/skatteetaten/ao/pkg/fileutil"
 	"io/ioutil"
 	"os"
@@ -27,16 +28,23 @@ type SetupParamsPayload struct {
 	//DryRun    bool                       `json:"dryRun"`
 }
 
+// Without an AuroraConfig, Boober deploys from the AuroraConfig it has stored
 type SetupCommand struct {
-	Affiliation  string              `json:"affiliation"`
-	AuroraConfig AuroraConfigPayload `json:"auroraConfig"`
-	SetupParams  SetupParamsPayload  `json:"setupParams"`
+	Affiliation  string               `json:"affiliation"`
+	AuroraConfig *AuroraConfigPayload `json:"auroraConfig,omitempty"`
+	SetupParams  SetupParamsPayload   `json:"setupParams"`
 }
 
 type OverrideJson struct {
 	Override map[string]json.RawMessage `json:"override"`
 }
 
+// An override of a single AuroraConfig file, either a RFC 7396 merge patch or a RFC 6902 JSON Patch
+type Override struct {
+	Filename string
+	Patch    json.RawMessage
+}
+
 // Search a json string for a secretFolder attribute
 func Json2secretFolder(jsonMessage json.RawMessage) (string, error) {
 	type FileStruct struct {
@@ -61,15 +69,76 @@ func Overrides2map(overrideJson []string, overrideFiles []string) (returnMap map
 func OverrideJsons2map(OverrideJsons []string) (returnMap map[string]json.RawMessage, err error) {
 	returnMap = make(map[string]json.RawMessage)
 
-	for i := 0; i < len(OverrideJsons); i++ {
-		indexByte := strings.IndexByte(OverrideJsons[i], ':')
-		filename := OverrideJsons[i][:indexByte]
-		jsonOverride := OverrideJsons[i][indexByte+1:]
-		returnMap[filename] = json.RawMessage(jsonOverride)
+	overrides, err := ParseOverrides(OverrideJsons, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		returnMap[override.Filename] = override.Patch
 	}
 	return returnMap, err
 }
 
+// Parses overrides given as filename:json strings, and overrides given as filename:path where the
+// json is read from the file at path.  The overrides are validated and returned in the order given.
+func ParseOverrides(overrideJsons []string, overrideFiles []string) (overrides []Override, err error) {
+	for _, overrideJson := range overrideJsons {
+		filename, jsonOverride, err := splitOverride(overrideJson)
+		if err != nil {
+			return nil, err
+		}
+		override, err := newOverride(filename, []byte(jsonOverride))
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+
+	for _, overrideFile := range overrideFiles {
+		filename, path, err := splitOverride(overrideFile)
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.New("Unable to read override file " + path + ": " + err.Error())
+		}
+		override, err := newOverride(filename, content)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+func splitOverride(override string) (filename string, value string, err error) {
+	indexByte := strings.IndexByte(override, ':')
+	if indexByte < 1 {
+		return "", "", errors.New("Illegal override " + override + ", expected [env/]file.json:<override>")
+	}
+	return override[:indexByte], override[indexByte+1:], nil
+}
+
+func newOverride(filename string, content []byte) (override Override, err error) {
+	if !strings.HasSuffix(filename, ".json") {
+		return override, errors.New("Illegal override file name " + filename + ", expected [env/]file.json")
+	}
+	override.Filename = filename
+
+	var patch interface{}
+	if err := json.Unmarshal(content, &patch); err != nil {
+		return override, errors.New("Illegal JSON in override for " + filename + ": " + err.Error())
+	}
+	switch patch.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return override, errors.New("Illegal override for " + filename + ": expected a JSON object (merge patch) or a JSON array (JSON Patch)")
+	}
+	override.Patch = json.RawMessage(content)
+	return override, nil
+}
+
 func JsonFolder2Map(folder string, prefix string) (map[string]json.RawMessage, error) {
 	returnMap := make(map[string]json.RawMessage)
 	var allFilesOK bool = true
@@ -122,13 +191,13 @@ func SecretFolder2Map(folder string) (map[string]string, error) {
 				output += fmt.Sprintf("This secret is just too big: %v\n", absolutePath)
 				allFilesOK = false
 			}
-			fileText, err := ioutil.ReadFile(absolutePath)
-			fileTextBase64 := base64.StdEncoding.EncodeToString(fileText)
+			fileText, secretName, err := cryptutil.ReadSecretFile(absolutePath)
 			if err != nil {
-				output += fmt.Sprintf("Error in reading Secret file %v\n", absolutePath)
+				output += fmt.Sprintf("Error in reading Secret file %v: %v\n", absolutePath, err)
 				allFilesOK = false
 			} else {
-				returnMap[absolutePath] = fileTextBase64
+				fileTextBase64 := base64.StdEncoding.EncodeToString(fileText)
+				returnMap[filepath.Join(folder, secretName)] = fileTextBase64
 			}
 		}
 	}
@@ -140,3 +140,73 @@ func TestPrettyPrintJson(t *testing.T) {
 		t.Error("Did not pretty print correctly")
 	}
 }
+
+func TestApplyMergePatch(t *testing.T) {
+	document := json.RawMessage(`{"replicas": 2, "route": true, "config": {"A": "1", "B": "2"}}`)
+	patch := json.RawMessage(`{"replicas": 3, "route": null, "config": {"B": "3"}}`)
+	expected := `{"config":{"A":"1","B":"3"},"replicas":3}`
+
+	res, err := ApplyMergePatch(document, patch)
+	if err != nil {
+		t.Errorf("ApplyMergePatch returned an error: %v", err.Error())
+	}
+	if string(res) != expected {
+		t.Errorf("ApplyMergePatch returned %v, expected %v", string(res), expected)
+	}
+}
+
+func TestApplyJsonPatch(t *testing.T) {
+	document := json.RawMessage(`{"replicas": 2, "route": true, "groups": ["a", "b"]}`)
+	patch := json.RawMessage(`[
+		{"op": "test", "path": "/replicas", "value": 2},
+		{"op": "replace", "path": "/replicas", "value": 3},
+		{"op": "remove", "path": "/route"},
+		{"op": "add", "path": "/groups/-", "value": "c"},
+		{"op": "copy", "from": "/groups/0", "path": "/owner"}
+	]`)
+	expected := `{"groups":["a","b","c"],"owner":"a","replicas":3}`
+
+	res, err := ApplyJsonPatch(document, patch)
+	if err != nil {
+		t.Errorf("ApplyJsonPatch returned an error: %v", err.Error())
+	}
+	if string(res) != expected {
+		t.Errorf("ApplyJsonPatch returned %v, expected %v", string(res), expected)
+	}
+
+	_, err = ApplyJsonPatch(document, json.RawMessage(`[{"op": "test", "path": "/replicas", "value": 5}]`))
+	if err == nil {
+		t.Error("ApplyJsonPatch did not fail on a failing test operation")
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	original := json.RawMessage(`{"replicas": 2, "route": true, "config": {"A": "1"}}`)
+	modified := json.RawMessage(`{"replicas": 2, "config": {"A": "2"}}`)
+	expected := `{"config":{"A":"2"},"route":null}`
+
+	res, err := CreateMergePatch(original, modified)
+	if err != nil {
+		t.Errorf("CreateMergePatch returned an error: %v", err.Error())
+	}
+	if string(res) != expected {
+		t.Errorf("CreateMergePatch returned %v, expected %v", string(res), expected)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides([]string{`utv/app.json:{"replicas": 1}`}, nil)
+	if err != nil {
+		t.Errorf("ParseOverrides returned an error: %v", err.Error())
+	}
+	if len(overrides) != 1 || overrides[0].Filename != "utv/app.json" {
+		t.Errorf("ParseOverrides returned unexpected result: %v", overrides)
+	}
+
+	illegalOverrides := []string{`utv/app.json:{"replicas": }`, `{"replicas": 1}`, `utv/app.json:"text"`, `utv/app:{"replicas": 1}`}
+	for _, illegalOverride := range illegalOverrides {
+		if _, err := ParseOverrides([]string{illegalOverride}, nil); err == nil {
+			t.Errorf("ParseOverrides accepted illegal override %v", illegalOverride)
+		}
+	}
+}
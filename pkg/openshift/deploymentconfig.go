@@ -0,0 +1,189 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// The parts of an OpenShift DeploymentConfig that ao cares about
+type DeploymentConfig struct {
+	Metadata struct {
+		Name       string            `json:"name"`
+		Namespace  string            `json:"namespace"`
+		Generation int               `json:"generation"`
+		Labels     map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas int `json:"replicas"`
+		Triggers []struct {
+			Type              string `json:"type"`
+			ImageChangeParams struct {
+				From objectReference `json:"from"`
+			} `json:"imageChangeParams"`
+		} `json:"triggers"`
+		Template struct {
+			Spec struct {
+				Containers []struct {
+					Name  string `json:"name"`
+					Image string `json:"image"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		LatestVersion       int `json:"latestVersion"`
+		ObservedGeneration  int `json:"observedGeneration"`
+		Replicas            int `json:"replicas"`
+		UpdatedReplicas     int `json:"updatedReplicas"`
+		AvailableReplicas   int `json:"availableReplicas"`
+		UnavailableReplicas int `json:"unavailableReplicas"`
+	} `json:"status"`
+}
+
+type objectReference struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// The parts of an OpenShift ImageStream that ao cares about
+type ImageStream struct {
+	Spec struct {
+		Tags []struct {
+			Name string          `json:"name"`
+			From objectReference `json:"from"`
+		} `json:"tags"`
+	} `json:"spec"`
+}
+
+// Returns the image of the first container in the pod template
+func (dc *DeploymentConfig) GetImage() string {
+	if len(dc.Spec.Template.Spec.Containers) == 0 {
+		return ""
+	}
+	return dc.Spec.Template.Spec.Containers[0].Image
+}
+
+// Returns the tag of the image of the first container, or "" if the image is referenced by digest
+func (dc *DeploymentConfig) GetImageTag() string {
+	return imageTag(dc.GetImage())
+}
+
+// Returns the tag of an image reference, or "" if the image is referenced by digest or has no tag
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	// A colon before the last slash belongs to the registry host, not the tag
+	lastSlash := strings.LastIndex(image, "/")
+	lastColon := strings.LastIndex(image, ":")
+	if lastColon <= lastSlash {
+		return ""
+	}
+	return image[lastColon+1:]
+}
+
+// Returns the ImageStreamTag the DeploymentConfig is updated from by an ImageChange trigger
+func (dc *DeploymentConfig) getTriggerImageStreamTag() (namespace string, name string, tag string, found bool) {
+	for _, trigger := range dc.Spec.Triggers {
+		from := trigger.ImageChangeParams.From
+		if trigger.Type != "ImageChange" || from.Kind != "ImageStreamTag" {
+			continue
+		}
+		namespace = from.Namespace
+		if namespace == "" {
+			namespace = dc.Metadata.Namespace
+		}
+		index := strings.LastIndex(from.Name, ":")
+		if index < 0 {
+			return namespace, from.Name, "latest", true
+		}
+		return namespace, from.Name[:index], from.Name[index+1:], true
+	}
+	return "", "", "", false
+}
+
+// Returns the image the tag in the ImageStream is imported from, or "" if the tag is not from a docker image
+func (imageStream *ImageStream) getTagImage(tag string) string {
+	for _, specTag := range imageStream.Spec.Tags {
+		if specTag.Name == tag && specTag.From.Kind == "DockerImage" {
+			return specTag.From.Name
+		}
+	}
+	return ""
+}
+
+// Returns the version that is deployed.  A DeploymentConfig that is updated by an ImageChange trigger runs the image
+// by digest, so the version is the tag of the image the ImageStream tag is imported from.  If the version can not be
+// found, the image is returned.
+func (cluster *OpenshiftCluster) GetDeployedVersion(dc *DeploymentConfig) (string, error) {
+	if tag := dc.GetImageTag(); tag != "" {
+		return tag, nil
+	}
+	if namespace, name, tag, found := dc.getTriggerImageStreamTag(); found {
+		var imageStream ImageStream
+		_, err := cluster.getResource(fmt.Sprintf("%s/oapi/v1/namespaces/%s/imagestreams/%s", cluster.Url, namespace, name),
+			"ImageStream "+namespace+"/"+name, &imageStream)
+		if err != nil {
+			return "", err
+		}
+		if version := imageTag(imageStream.getTagImage(tag)); version != "" {
+			return version, nil
+		}
+	}
+	return dc.GetImage(), nil
+}
+
+// Reads a DeploymentConfig from the cluster.  Returns nil and no error if it does not exist.
+func (cluster *OpenshiftCluster) GetDeploymentConfig(namespace string, name string) (*DeploymentConfig, error) {
+	var dc DeploymentConfig
+	exists, err := cluster.getResource(fmt.Sprintf("%s/oapi/v1/namespaces/%s/deploymentconfigs/%s", cluster.Url, namespace, name),
+		"DeploymentConfig "+namespace+"/"+name, &dc)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return &dc, nil
+}
+
+// Reads a resource from the cluster into resource.  Returns false and no error if it does not exist.
+func (cluster *OpenshiftCluster) getResource(url string, description string, resource interface{}) (exists bool, err error) {
+	if cluster.Token == "" {
+		return false, errors.New("Not logged in to cluster " + cluster.Name)
+	}
+
+	resp, err := getBearer(url, cluster.Token)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New("Unable to get " + description + " from " + cluster.Name + ": " + resp.Status)
+	}
+
+	if err := json.Unmarshal(body, resource); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Returns the cluster with the given name, or nil if it is not in the configuration
+func (openshiftConfig *OpenshiftConfig) GetCluster(clusterName string) *OpenshiftCluster {
+	for i := range openshiftConfig.Clusters {
+		if openshiftConfig.Clusters[i].Name == clusterName {
+			return openshiftConfig.Clusters[i]
+		}
+	}
+	return nil
+}
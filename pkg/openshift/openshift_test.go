@@ -48,3 +48,69 @@ func TestPing(t *testing.T) {
 
 	})
 }
+
+func TestGetImageTag(t *testing.T) {
+	testCases := []struct {
+		image string
+		tag   string
+	}{
+		{"docker-registry.aurora.sits.no:5000/no_skatteetaten_aurora/boober:1.2.3", "1.2.3"},
+		{"docker-registry.aurora.sits.no:5000/no_skatteetaten_aurora/boober", ""},
+		{"docker-registry.aurora.sits.no:5000/no_skatteetaten_aurora/boober@sha256:abcdef", ""},
+	}
+	for _, tc := range testCases {
+		var dc DeploymentConfig
+		dc.Spec.Template.Spec.Containers = append(dc.Spec.Template.Spec.Containers, struct {
+			Name  string `json:"name"`
+			Image string `json:"image"`
+		}{Name: "boober", Image: tc.image})
+
+		if tag := dc.GetImageTag(); tag != tc.tag {
+			t.Errorf("GetImageTag returned %v for %v, expected %v", tag, tc.image, tc.tag)
+		}
+	}
+}
+
+func TestGetDeployedVersion(t *testing.T) {
+	defer gock.Off()
+	gock.InterceptClient(&client)
+
+	cluster := OpenshiftCluster{Name: "utv", Url: "https://utv.test:8443", Token: "token"}
+	gock.New(cluster.Url).
+		Get("/oapi/v1/namespaces/paas-test/deploymentconfigs/api").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "api", "namespace": "paas-test"},
+			"spec": map[string]interface{}{
+				"triggers": []interface{}{
+					map[string]interface{}{"type": "ConfigChange"},
+					map[string]interface{}{"type": "ImageChange", "imageChangeParams": map[string]interface{}{
+						"from": map[string]interface{}{"kind": "ImageStreamTag", "name": "api:default"}}},
+				},
+				"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "api", "image": "registry.test:5000/paas/api@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"},
+				}}},
+			},
+		})
+	gock.New(cluster.Url).
+		Get("/oapi/v1/namespaces/paas-test/imagestreams/api").
+		Reply(200).
+		JSON(map[string]interface{}{"spec": map[string]interface{}{"tags": []interface{}{
+			map[string]interface{}{"name": "default", "from": map[string]interface{}{"kind": "DockerImage", "name": "registry.test:5000/paas/api:1.2.3"}},
+		}}})
+
+	dc, err := cluster.GetDeploymentConfig("paas-test", "api")
+	if err != nil || dc == nil {
+		t.Fatalf("GetDeploymentConfig failed: %v", err)
+	}
+	if tag := dc.GetImageTag(); tag != "" {
+		t.Errorf("Expected no tag for an image referenced by digest, got %v", tag)
+	}
+	version, err := cluster.GetDeployedVersion(dc)
+	if err != nil {
+		t.Fatalf("GetDeployedVersion failed: %v", err)
+	}
+	if version != "1.2.3" {
+		t.Errorf("Expected the version from the ImageStream, got %v", version)
+	}
+}
@@ -75,16 +75,18 @@ type Response struct {
 }
 
 type ResponseItemError struct {
-	Application string `json:"application"`
-	Environment string `json:"environment"`
-	Messages    []struct {
-		Message string `json:"message"`
-		Field   struct {
-			Path   string `json:"path"`
-			Value  string `json:"value"`
-			Source string `json:"source"`
-		} `json:"field"`
-	} `json:"messages"`
+	Application string                `json:"application"`
+	Environment string                `json:"environment"`
+	Messages    []ResponseItemMessage `json:"messages"`
+}
+
+type ResponseItemMessage struct {
+	Message string `json:"message"`
+	Field   struct {
+		Path   string `json:"path"`
+		Value  string `json:"value"`
+		Source string `json:"source"`
+	} `json:"field"`
 }
 
 type AuroraConfig struct {
@@ -123,6 +125,15 @@ const apiNotInstalledResponse = "Application is not available"
 const localhostAddress = "localhost"
 const localhostPort = "8080"
 
+// Prefix of the error message when Boober rejects the token
+const UnauthorizedMessage = "Not authorized"
+
+// Returned by CallApiConditional when the content is not changed
+var ErrNotModified = errors.New("Not modified")
+
+// The time allowed for each request to Boober and the Console.  No timeout if zero
+var RequestTimeout time.Duration
+
 func ParsePingResult(responseString string) (PingResult PingResult, err error) {
 	var responseData []byte
 	responseData = []byte(responseString)
@@ -157,6 +168,18 @@ func ResponseItems2ApplicationResults(response Response) (applicationResults []A
 	return
 }
 
+func ResponseItems2ResponseItemErrors(response Response) (responseItemErrors []ResponseItemError, err error) {
+	responseItemErrors = make([]ResponseItemError, len(response.Items))
+
+	for item := range response.Items {
+		err = json.Unmarshal([]byte(response.Items[item]), &responseItemErrors[item])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 func ResponseItems2AuroraConfig(response Response) (auroraConfig AuroraConfig, err error) {
 
 	if response.Count > 1 {
@@ -235,6 +258,11 @@ func ResponsItems2MessageString(response Response) (output string, err error) {
 	return
 }
 
+// Returns true if the message is the result of Boober rejecting the token
+func IsUnauthorized(message string) bool {
+	return strings.Contains(message, UnauthorizedMessage)
+}
+
 func getConsoleAddress(clusterName string) (consoleAddress string) {
 	//consoleAddress = "http://console-aurora." + clusterName + ".paas.skead.no"
 	consoleAddress = "http://console-paas-espen-dev." + clusterName + ".paas.skead.no"
@@ -260,7 +288,7 @@ func CallConsole(apiEndpoint string, arguments string, verbose bool, debug bool,
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: RequestTimeout}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -334,6 +362,53 @@ func CallApiShort(httpMethod string, apiEndpoint string, jsonRequestBody string,
 		persistentOptions.Token)
 }
 
+// Sends a GET request to the API cluster.  The validators from an earlier response are sent as If-None-Match and
+// If-Modified-Since, and ErrNotModified is returned if the content is not changed.  The validators of the response
+// are returned, empty if Boober does not send them.
+func CallApiConditional(apiEndpoint string, etag string, lastModified string, config *configuration.ConfigurationClass) (output string,
+	newEtag string, newLastModified string, err error) {
+
+	persistentOptions := config.PersistentOptions
+	openshiftConfig := config.OpenshiftConfig
+	token := persistentOptions.Token
+	var apiAddress string
+
+	if persistentOptions.Localhost || openshiftConfig.Localhost {
+		apiAddress = "http://" + localhostAddress + ":" + localhostPort
+		if apiCluster, err := openshiftConfig.GetApiCluster(); err == nil && token == "" {
+			token = apiCluster.Token
+		}
+	} else {
+		apiCluster, err := openshiftConfig.GetApiCluster()
+		if err != nil {
+			return "", "", "", err
+		}
+		if !apiCluster.Reachable {
+			return "", "", "", errors.New("The API cluster " + apiCluster.Name + " is not reachable")
+		}
+		if apiCluster.BooberUrl == "" {
+			return "", "", "", errors.New("Boober URL is not configured, please log in again")
+		}
+		apiAddress = apiCluster.BooberUrl
+		if token == "" {
+			token = apiCluster.Token
+		}
+	}
+
+	headers := make(map[string]string)
+	if etag != "" {
+		headers["If-None-Match"] = etag
+	}
+	if lastModified != "" {
+		headers["If-Modified-Since"] = lastModified
+	}
+
+	var responseHeader http.Header
+	output, err = callApiInstance(headers, http.MethodGet, "", persistentOptions.Verbose, apiAddress+apiEndpoint, token,
+		persistentOptions.DryRun, persistentOptions.Debug, &responseHeader)
+	return output, responseHeader.Get("ETag"), responseHeader.Get("Last-Modified"), err
+}
+
 /*
 func CallApiWithConfig(headers map[string]string, httpMethod string, apiEndpoint string, combindedJson string, configuration *configuration.ConfigurationClass) (outputMap map[string]string, err error) {
 	//return CallApiWithHeaders (headers, httpMethod, apiEndpoint, combindedJson, api,  )
@@ -358,7 +433,7 @@ func CallApiWithHeaders(headers map[string]string, httpMethod string, apiEndpoin
 		}
 		output, err := callApiInstance(headers, httpMethod, combindedJson, verbose,
 			apiAddress+apiEndpoint,
-			token, dryRun, debug)
+			token, dryRun, debug, nil)
 		outputMap[openshiftConfig.Clusters[0].Name] = output
 		if err != nil {
 			return outputMap, err
@@ -378,7 +453,7 @@ func CallApiWithHeaders(headers map[string]string, httpMethod string, apiEndpoin
 					}
 					output, err := callApiInstance(headers, httpMethod, combindedJson, verbose,
 						openshiftConfig.Clusters[i].BooberUrl+apiEndpoint,
-						token, dryRun, debug)
+						token, dryRun, debug, nil)
 					outputMap[openshiftConfig.Clusters[i].Name] = output
 
 					if err != nil {
@@ -420,7 +495,9 @@ func makeResponse(message string, success bool) (responseStr string, err error)
 	return responseStr, err
 }
 
-func callApiInstance(headers map[string]string, httpMethod string, combindedJson string, verbose bool, url string, token string, dryRun bool, debug bool) (output string, err error) {
+// If responseHeader is given, it is set to the headers of the response
+func callApiInstance(headers map[string]string, httpMethod string, combindedJson string, verbose bool, url string, token string, dryRun bool, debug bool,
+	responseHeader *http.Header) (output string, err error) {
 
 	if verbose {
 		fmt.Print("Sending config to Boober at " + url + "... ")
@@ -459,7 +536,7 @@ func callApiInstance(headers map[string]string, httpMethod string, combindedJson
 		}
 	}
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: RequestTimeout}
 
 	startTime := time.Now()
 	resp, err := client.Do(req)
@@ -475,6 +552,9 @@ func callApiInstance(headers map[string]string, httpMethod string, combindedJson
 	defer resp.Body.Close()
 	body, _ := ioutil.ReadAll(resp.Body)
 	output = string(body)
+	if responseHeader != nil {
+		*responseHeader = resp.Header
+	}
 
 	if debug {
 		fmt.Println("RESPONSE:")
@@ -488,6 +568,32 @@ func callApiInstance(headers map[string]string, httpMethod string, combindedJson
 		fmt.Println("\tResponse time: " + strconv.FormatFloat(requestTime.Seconds(), 'f', 2, 64) + " sec")
 	}
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		// The response from Boober is kept for the callers, only the error gets a prefix that lets the deploy
+		// tell a rejected token from other failures
+		errorstring := fmt.Sprintf(UnauthorizedMessage+" on %v: %v", url, resp.Status)
+		if response, err := ParseResponse(output); err == nil {
+			if response.Message != "" {
+				errorstring += ": " + response.Message
+			}
+			if verbose {
+				fmt.Println(errorstring)
+			}
+			return output, errors.New(errorstring)
+		}
+		if verbose {
+			fmt.Println(errorstring)
+		}
+		return makeResponse(errorstring, false)
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		if verbose {
+			fmt.Println("Not modified")
+		}
+		return "", ErrNotModified
+	}
+
 	if jsonutil.IsLegalJson(output) {
 		response, err := ParseResponse(output)
 		if err != nil {
@@ -0,0 +1,101 @@
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/fileutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+// Name of the permissions file written by export.  Import accepts any file with "permission" in the name.
+const permissionsFilename = "permissions.json"
+
+// Exports one vault, or all vaults in the affiliation, to <folder>/<vaultname>.
+// The secrets are decoded and written one file per secret together with a permissions file,
+// which is the layout read by vault import and vault create --folder.
+func ExportVaults(vaultName string, all bool, folderName string, config *configuration.ConfigurationClass) (output string, err error) {
+	var vaults []serverapi.Vault
+	if all {
+		vaults, err = auroraconfig.GetVaultsArray(config)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		vault, err := auroraconfig.GetVault(vaultName, config)
+		if err != nil {
+			return "", err
+		}
+		vaults = append(vaults, vault)
+	}
+
+	if fileutil.IsLegalFileFolder(folderName) == fileutil.SpecIsFile {
+		return "", errors.New("Error: " + folderName + " is a file")
+	}
+	if err := os.MkdirAll(folderName, 0700); err != nil {
+		return "", err
+	}
+
+	for _, vault := range vaults {
+		if err := vault2SecretsFolder(vault, folderName); err != nil {
+			return output, err
+		}
+		output += "Exported vault " + vault.Name + " with " + strconv.Itoa(len(vault.Secrets)) + " secrets\n"
+	}
+	return output, nil
+}
+
+func vault2SecretsFolder(vault serverapi.Vault, folderName string) (err error) {
+	if vault.Name == "" || strings.ContainsAny(vault.Name, "/\\") || vault.Name == "." || vault.Name == ".." {
+		return errors.New("Error: Illegal vault name " + vault.Name)
+	}
+	vaultFolder := filepath.Join(folderName, vault.Name)
+	if fileutil.IsLegalFileFolder(vaultFolder) != fileutil.SpecIllegal {
+		return errors.New("Error: " + vaultFolder + " exists")
+	}
+
+	// Check all the secrets before writing anything, so that a vault is either exported completely or not at all
+	var secretNames []string
+	secrets := make(map[string][]byte)
+	for secretName, secret64 := range vault.Secrets {
+		if secretName == "" || strings.ContainsAny(secretName, "/\\") || secretName == "." || secretName == ".." {
+			return errors.New("Error: Illegal secret name " + secretName + " in vault " + vault.Name)
+		}
+		if strings.Contains(secretName, "permission") {
+			return errors.New("Error: Secret " + secretName + " in vault " + vault.Name + " would be read as a permissions file on import")
+		}
+		secrets[secretName], err = base64.StdEncoding.DecodeString(secret64)
+		if err != nil {
+			return errors.New("Error: Secret " + secretName + " in vault " + vault.Name + " is not legal base64: " + err.Error())
+		}
+		secretNames = append(secretNames, secretName)
+	}
+	sort.Strings(secretNames)
+
+	permissions, err := json.MarshalIndent(vault.Permissions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(vaultFolder, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(vaultFolder, permissionsFilename), permissions, 0600); err != nil {
+		return err
+	}
+	for _, secretName := range secretNames {
+		if err := ioutil.WriteFile(filepath.Join(vaultFolder, secretName), secrets[secretName], 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package vault
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/executil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+var errCancelled = errors.New("Operation cancelled by user")
+
+func vaultReferencePatterns(vaultName string) []*regexp.Regexp {
+	quotedName := regexp.QuoteMeta(`"` + vaultName + `"`)
+	return []*regexp.Regexp{
+		regexp.MustCompile(`("(?:` + secretFolderField + `|` + secretVaultField + `)"\s*:\s*)` + quotedName),
+		regexp.MustCompile(`("` + secretVaultField + `"\s*:\s*\{[^{}]*"name"\s*:\s*)` + quotedName),
+	}
+}
+
+// Replaces the vault name in the secretFolder and secretVault fields of an AuroraConfig file.
+// The rest of the file is kept as it is.
+func rewriteVaultReferences(content string, vaultName string, newName string) (rewritten string, changed bool) {
+	rewritten = content
+	for _, pattern := range vaultReferencePatterns(vaultName) {
+		rewritten = pattern.ReplaceAllString(rewritten, `${1}"`+strings.Replace(newName, "$", "$$", -1)+`"`)
+	}
+	return rewritten, rewritten != content
+}
+
+func findReferencingFiles(vaultName string, config *configuration.ConfigurationClass) (filenames []string, err error) {
+	ac, err := auroraconfig.GetAuroraConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	patterns := vaultReferencePatterns(vaultName)
+	for filename, content := range ac.Files {
+		for _, pattern := range patterns {
+			if pattern.Match(content) {
+				filenames = append(filenames, filename)
+				break
+			}
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+func moveError(text string, message string, err error) error {
+	if strings.TrimSpace(message) != "" {
+		return errors.New("Error: " + text + ": " + message)
+	}
+	return errors.New("Error: " + text + ": " + err.Error())
+}
+
+func vaultSummary(vault serverapi.Vault) (output string) {
+	var secretNames []string
+	for secretName := range vault.Secrets {
+		secretNames = append(secretNames, secretName)
+	}
+	sort.Strings(secretNames)
+	output += "\t" + strconv.Itoa(len(secretNames)) + " secrets: " + strings.Join(secretNames, ", ") + "\n"
+	output += "\tGroups: " + strings.Join(vault.Permissions.Groups, ", ") + "\n"
+	output += "\tUsers: " + strings.Join(vault.Permissions.Users, ", ") + "\n"
+	return output
+}
+
+func confirm(summary string, force bool) error {
+	if force {
+		return nil
+	}
+	response, err := executil.PromptYNC(summary + "Are you sure?")
+	if err != nil {
+		return err
+	}
+	if response != "Y" {
+		return errCancelled
+	}
+	return nil
+}
+
+// Returns a copy of the vault without the versions, to be created under a new name
+func copyVault(vault serverapi.Vault, newName string) serverapi.Vault {
+	vault.Name = newName
+	vault.Versions = nil
+	return vault
+}
+
+// Renames a vault.  The new vault is created with the same secrets and permissions, the references in the
+// AuroraConfig are changed to the new name, and the old vault is deleted last.
+func MoveVault(vaultName string, newName string, force bool, config *configuration.ConfigurationClass) (output string, err error) {
+	if vaultName == newName {
+		return "", errors.New("Error: Source and destination are the same")
+	}
+	vault, err := auroraconfig.GetVault(vaultName, config)
+	if err != nil {
+		return "", err
+	}
+	exists, err := vaultExists(newName, config)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errors.New("Error: Vault " + newName + " exists")
+	}
+	filenames, err := findReferencingFiles(vaultName, config)
+	if err != nil {
+		return "", err
+	}
+
+	summary := "Move vault " + vaultName + " to " + newName + ":\n" + vaultSummary(vault)
+	if len(filenames) > 0 {
+		summary += "\tReferences to update: " + strings.Join(filenames, ", ") + "\n"
+	}
+	if err := confirm(summary, force); err != nil {
+		return "", err
+	}
+
+	message, err := auroraconfig.PutVault(newName, copyVault(vault, newName), "", config)
+	if err != nil {
+		return "", moveError("Unable to create vault "+newName, message, err)
+	}
+	output += "Created vault " + newName + "\n"
+
+	for _, filename := range filenames {
+		content, version, err := auroraconfig.GetContent(filename, config)
+		if err != nil {
+			return output, err
+		}
+		rewritten, changed := rewriteVaultReferences(content, vaultName, newName)
+		if !changed {
+			continue
+		}
+		if message, err := auroraconfig.PutFile(filename, rewritten, version, config); err != nil {
+			return output, moveError("Unable to update "+filename+", vault "+vaultName+" is kept", message, err)
+		}
+		output += "Updated " + filename + "\n"
+	}
+
+	if message, err := auroraconfig.DeleteVault(vaultName, config); err != nil {
+		return output, moveError("Unable to delete vault "+vaultName, message, err)
+	}
+	output += "Deleted vault " + vaultName + "\n"
+	return output, nil
+}
+
+// Copies a vault with its secrets and permissions to another affiliation, or to a new name in the same affiliation.
+// An empty newName keeps the name.
+func CopyVault(vaultName string, newName string, toAffiliation string, force bool, config *configuration.ConfigurationClass) (output string, err error) {
+	if newName == "" {
+		newName = vaultName
+	}
+	fromAffiliation := config.GetAffiliation()
+	if toAffiliation == "" {
+		toAffiliation = fromAffiliation
+	}
+	if toAffiliation == fromAffiliation && newName == vaultName {
+		return "", errors.New("Error: Source and destination are the same")
+	}
+
+	vault, err := auroraconfig.GetVault(vaultName, config)
+	if err != nil {
+		return "", err
+	}
+
+	// The vault calls use the affiliation in the configuration
+	config.OpenshiftConfig.Affiliation = toAffiliation
+	defer func() {
+		config.OpenshiftConfig.Affiliation = fromAffiliation
+	}()
+
+	exists, err := vaultExists(newName, config)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errors.New("Error: Vault " + newName + " exists in affiliation " + toAffiliation)
+	}
+
+	summary := "Copy vault " + fromAffiliation + "/" + vaultName + " to " + toAffiliation + "/" + newName + ":\n" + vaultSummary(vault)
+	if err := confirm(summary, force); err != nil {
+		return "", err
+	}
+
+	message, err := auroraconfig.PutVault(newName, copyVault(vault, newName), "", config)
+	if err != nil {
+		return "", moveError("Unable to create vault "+newName+" in affiliation "+toAffiliation, message, err)
+	}
+	return "Copied vault " + fromAffiliation + "/" + vaultName + " to " + toAffiliation + "/" + newName + "\n", nil
+}
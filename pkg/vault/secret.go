@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+// The secret commands use the per secret endpoint, and send the version of the secret so that
+// concurrent changes are rejected by Boober instead of overwritten.
+
+func validateSecretName(secretName string) error {
+	if secretName == "" || strings.ContainsAny(secretName, "/\\") || secretName == "." || secretName == ".." {
+		return errors.New("Error: Illegal secret name " + secretName)
+	}
+	return nil
+}
+
+func getSecretContent(vault serverapi.Vault, secretName string) (content []byte, version string, err error) {
+	secret64, found := vault.Secrets[secretName]
+	if !found {
+		return nil, "", errors.New("Error: No such secret " + secretName + " in vault " + vault.Name)
+	}
+	content, err = base64.StdEncoding.DecodeString(secret64)
+	if err != nil {
+		return nil, "", errors.New("Error: Secret " + secretName + " in vault " + vault.Name + " is not legal base64: " + err.Error())
+	}
+	return content, vault.Versions[secretName], nil
+}
+
+func putSecret(vault serverapi.Vault, secretName string, content []byte, overwrite bool, config *configuration.ConfigurationClass) (err error) {
+	if err := validateSecretName(secretName); err != nil {
+		return err
+	}
+	_, exists := vault.Secrets[secretName]
+	if exists && !overwrite {
+		return errors.New("Error: Secret " + secretName + " exists in vault " + vault.Name + ", use --overwrite to replace it")
+	}
+	// A new secret is sent without a version
+	var version string
+	if exists {
+		version = vault.Versions[secretName]
+	}
+
+	message, err := auroraconfig.PutSecret(vault.Name, secretName, string(content), version, config)
+	if err != nil {
+		if message != "" {
+			return errors.New(message)
+		}
+		return err
+	}
+	return nil
+}
+
+func deleteSecret(vault serverapi.Vault, secretName string, version string, config *configuration.ConfigurationClass) (err error) {
+	message, err := auroraconfig.DeleteSecret(vault.Name, secretName, version, config)
+	if err != nil {
+		if message != "" {
+			return errors.New(message)
+		}
+		return err
+	}
+	return nil
+}
+
+func AddSecret(vaultName string, secretName string, content []byte, overwrite bool, config *configuration.ConfigurationClass) (output string, err error) {
+	vault, err := auroraconfig.GetVault(vaultName, config)
+	if err != nil {
+		return "", err
+	}
+	if err := putSecret(vault, secretName, content, overwrite, config); err != nil {
+		return "", err
+	}
+	return "Added secret " + secretName + " to vault " + vaultName + "\n", nil
+}
+
+func RemoveSecret(vaultName string, secretName string, config *configuration.ConfigurationClass) (output string, err error) {
+	vault, err := auroraconfig.GetVault(vaultName, config)
+	if err != nil {
+		return "", err
+	}
+	_, version, err := getSecretContent(vault, secretName)
+	if err != nil {
+		return "", err
+	}
+	if err := deleteSecret(vault, secretName, version, config); err != nil {
+		return "", err
+	}
+	return "Removed secret " + secretName + " from vault " + vaultName + "\n", nil
+}
+
+// Copies a secret to another vault, or to a new name in the same vault.  An empty toSecretName keeps the name.
+func CopySecret(vaultName string, secretName string, toVaultName string, toSecretName string, overwrite bool,
+	config *configuration.ConfigurationClass) (output string, err error) {
+
+	_, err = copySecret(vaultName, secretName, toVaultName, toSecretName, overwrite, config)
+	if err != nil {
+		return "", err
+	}
+	if toSecretName == "" {
+		toSecretName = secretName
+	}
+	return "Copied secret " + vaultName + "/" + secretName + " to " + toVaultName + "/" + toSecretName + "\n", nil
+}
+
+// Moves a secret to another vault, or renames it within the vault.  The secret is removed only after the copy is saved.
+func MoveSecret(vaultName string, secretName string, toVaultName string, toSecretName string, overwrite bool,
+	config *configuration.ConfigurationClass) (output string, err error) {
+
+	if toSecretName == "" {
+		toSecretName = secretName
+	}
+	if vaultName == toVaultName && secretName == toSecretName {
+		return "", errors.New("Error: Source and destination are the same")
+	}
+
+	vault, err := copySecret(vaultName, secretName, toVaultName, toSecretName, overwrite, config)
+	if err != nil {
+		return "", err
+	}
+	if err := deleteSecret(vault, secretName, vault.Versions[secretName], config); err != nil {
+		return "", errors.New("Secret copied to " + toVaultName + "/" + toSecretName + ", but not removed from " + vaultName + ": " + err.Error())
+	}
+	return "Moved secret " + vaultName + "/" + secretName + " to " + toVaultName + "/" + toSecretName + "\n", nil
+}
+
+// Returns the source vault as it was read, so that the caller can use the versions of its secrets
+func copySecret(vaultName string, secretName string, toVaultName string, toSecretName string, overwrite bool,
+	config *configuration.ConfigurationClass) (vault serverapi.Vault, err error) {
+
+	if toSecretName == "" {
+		toSecretName = secretName
+	}
+	vault, err = auroraconfig.GetVault(vaultName, config)
+	if err != nil {
+		return vault, err
+	}
+	content, _, err := getSecretContent(vault, secretName)
+	if err != nil {
+		return vault, err
+	}
+
+	toVault := vault
+	if toVaultName != vaultName {
+		toVault, err = auroraconfig.GetVault(toVaultName, config)
+		if err != nil {
+			return vault, err
+		}
+	}
+	return vault, putSecret(toVault, toSecretName, content, overwrite, config)
+}
@@ -0,0 +1,200 @@
+package vault
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+type vaultDiff struct {
+	added              []string
+	changed            []string
+	removed            []string
+	permissionsChanged bool
+}
+
+func (diff vaultDiff) isEmpty() bool {
+	return len(diff.added) == 0 && len(diff.changed) == 0 && len(diff.removed) == 0 && !diff.permissionsChanged
+}
+
+// Compares a local vault with the server vault by secret name and base64 content.
+// The permissions are only compared if the local vault has a permissions file with content.
+func diffVault(local serverapi.Vault, remote serverapi.Vault) (diff vaultDiff) {
+	for secretName, secret64 := range local.Secrets {
+		remoteSecret64, found := remote.Secrets[secretName]
+		if !found {
+			diff.added = append(diff.added, secretName)
+		} else if remoteSecret64 != secret64 {
+			diff.changed = append(diff.changed, secretName)
+		}
+	}
+	for secretName := range remote.Secrets {
+		if _, found := local.Secrets[secretName]; !found {
+			diff.removed = append(diff.removed, secretName)
+		}
+	}
+	sort.Strings(diff.added)
+	sort.Strings(diff.changed)
+	sort.Strings(diff.removed)
+
+	if len(local.Permissions.Groups) > 0 || len(local.Permissions.Users) > 0 {
+		diff.permissionsChanged = !sameElements(local.Permissions.Groups, remote.Permissions.Groups) ||
+			!sameElements(local.Permissions.Users, remote.Permissions.Users)
+	}
+	return diff
+}
+
+func sameElements(list1 []string, list2 []string) bool {
+	if len(list1) != len(list2) {
+		return false
+	}
+	sorted1 := append([]string{}, list1...)
+	sorted2 := append([]string{}, list2...)
+	sort.Strings(sorted1)
+	sort.Strings(sorted2)
+	for i := range sorted1 {
+		if sorted1[i] != sorted2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Lists the differences by secret name.  The values are never printed.
+func formatVaultDiff(vaultName string, diff vaultDiff, created bool, prune bool) (output string) {
+	if created {
+		output += vaultName + " (new vault):\n"
+	} else {
+		output += vaultName + ":\n"
+	}
+	if diff.isEmpty() {
+		return output + "\tNo changes\n"
+	}
+	for _, secretName := range diff.added {
+		output += "\t+ " + secretName + "\n"
+	}
+	for _, secretName := range diff.changed {
+		output += "\t~ " + secretName + "\n"
+	}
+	for _, secretName := range diff.removed {
+		if prune {
+			output += "\t- " + secretName + "\n"
+		} else {
+			output += "\t  " + secretName + " (only on server, use --prune to remove)\n"
+		}
+	}
+	if diff.permissionsChanged {
+		output += "\t~ permissions\n"
+	}
+	return output
+}
+
+func readLocalVaults(folderName string) (vaults []serverapi.Vault, err error) {
+	folderCount, err := countFolders(folderName)
+	if err != nil {
+		return nil, err
+	}
+	// A folder without subfolders is a single vault, as in vault create --folder
+	if folderCount == 0 {
+		vault, err := secretsFolder2Vault(filepath.Clean(folderName))
+		if err != nil {
+			return nil, err
+		}
+		return []serverapi.Vault{vault}, nil
+	}
+	return vaultsFolder2VaultsArray(folderName)
+}
+
+// Converges the server vaults onto the local folder, which has the same layout as for vault import.
+// Only the secrets that differ are sent.  Secrets that are only on the server are removed if prune is true.
+// Vaults that are only on the server are never removed.
+func SyncVaults(folderName string, prune bool, dryRun bool, config *configuration.ConfigurationClass) (output string, err error) {
+	localVaults, err := readLocalVaults(folderName)
+	if err != nil {
+		return "", err
+	}
+	remoteVaults, err := auroraconfig.GetVaultsArray(config)
+	if err != nil {
+		return "", err
+	}
+	remoteExists := make(map[string]bool)
+	for _, remote := range remoteVaults {
+		remoteExists[remote.Name] = true
+	}
+
+	for _, local := range localVaults {
+		if !remoteExists[local.Name] {
+			var empty serverapi.Vault
+			output += formatVaultDiff(local.Name, diffVault(local, empty), true, prune)
+			if !dryRun {
+				message, err := auroraconfig.PutVault(local.Name, local, "", config)
+				if err != nil {
+					return output, syncError(local.Name, message, err)
+				}
+			}
+			continue
+		}
+
+		remote, err := auroraconfig.GetVault(local.Name, config)
+		if err != nil {
+			return output, err
+		}
+		diff := diffVault(local, remote)
+		output += formatVaultDiff(local.Name, diff, false, prune)
+		if dryRun || diff.isEmpty() {
+			continue
+		}
+		if err := applyVaultDiff(local, remote, diff, prune, config); err != nil {
+			return output, err
+		}
+	}
+
+	if dryRun {
+		output += "Dry run, no changes applied\n"
+	}
+	return output, nil
+}
+
+func applyVaultDiff(local serverapi.Vault, remote serverapi.Vault, diff vaultDiff, prune bool, config *configuration.ConfigurationClass) error {
+	for _, secretName := range append(append([]string{}, diff.added...), diff.changed...) {
+		content, _, err := getSecretContent(local, secretName)
+		if err != nil {
+			return err
+		}
+		if err := putSecret(remote, secretName, content, true, config); err != nil {
+			return syncError(local.Name, "", err)
+		}
+	}
+	if prune {
+		for _, secretName := range diff.removed {
+			if err := deleteSecret(remote, secretName, remote.Versions[secretName], config); err != nil {
+				return syncError(local.Name, "", err)
+			}
+		}
+	}
+	if diff.permissionsChanged {
+		// Read the vault again, so that the secrets changed above are kept
+		vault, err := auroraconfig.GetVault(local.Name, config)
+		if err != nil {
+			return err
+		}
+		vault.Permissions = local.Permissions
+		message, err := auroraconfig.PutVault(local.Name, vault, "", config)
+		if err != nil {
+			return syncError(local.Name, message, err)
+		}
+	}
+	return nil
+}
+
+func syncError(vaultName string, message string, err error) error {
+	if strings.TrimSpace(message) != "" {
+		return errors.New("Error: Unable to sync vault " + vaultName + ": " + message)
+	}
+	return errors.New("Error: Unable to sync vault " + vaultName + ": " + err.Error())
+}
@@ -0,0 +1,234 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skatteetaten/ao/pkg/auroraconfig"
+	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/jsonutil"
+	"github.com/skatteetaten/ao/pkg/printutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
+)
+
+// The fields in the AuroraConfig that reference a vault.  secretFolder holds the name of the vault.
+// secretVault is either the name of the vault, or an object with the name and the keys that are used:
+//
+//	"secretFolder": "myvault"
+//	"secretVault": "myvault"
+//	"secretVault": {"name": "myvault", "keys": ["db.password"]}
+const (
+	secretFolderField = "secretFolder"
+	secretVaultField  = "secretVault"
+)
+
+type vaultReference struct {
+	file  string
+	vault string
+	keys  []string
+}
+
+// Finds the vault references in each file of the AuroraConfig, sorted by vault and file
+func getVaultReferences(auroraConfig serverapi.AuroraConfig) (references []vaultReference, err error) {
+	for filename, content := range auroraConfig.Files {
+		var fileMap map[string]json.RawMessage
+		if err := json.Unmarshal(content, &fileMap); err != nil {
+			return nil, errors.New("Illegal JSON in " + filename + ": " + err.Error())
+		}
+
+		var vaultReferenceFound bool
+		if value, found := fileMap[secretVaultField]; found {
+			reference := vaultReference{file: filename}
+			if err := json.Unmarshal(value, &reference.vault); err != nil {
+				var vaultObject struct {
+					Name string   `json:"name"`
+					Keys []string `json:"keys"`
+				}
+				if err := json.Unmarshal(value, &vaultObject); err != nil {
+					return nil, errors.New("Illegal " + secretVaultField + " in " + filename)
+				}
+				reference.vault = vaultObject.Name
+				reference.keys = vaultObject.Keys
+			}
+			if reference.vault != "" {
+				references = append(references, reference)
+				vaultReferenceFound = true
+			}
+		}
+
+		if _, found := fileMap[secretFolderField]; found {
+			secretFolder, err := jsonutil.Json2secretFolder(content)
+			if err != nil {
+				return nil, errors.New("Illegal " + secretFolderField + " in " + filename)
+			}
+			// A file that names the same vault in both fields is only listed once
+			if secretFolder != "" && !(vaultReferenceFound && references[len(references)-1].vault == secretFolder) {
+				references = append(references, vaultReference{file: filename, vault: secretFolder})
+			}
+		}
+	}
+
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].vault != references[j].vault {
+			return references[i].vault < references[j].vault
+		}
+		return references[i].file < references[j].file
+	})
+	return references, nil
+}
+
+// A key is found if the vault has a secret with that name, or a properties secret that defines the key
+func vaultHasKey(vault serverapi.Vault, key string) bool {
+	if _, found := vault.Secrets[key]; found {
+		return true
+	}
+	for _, secret64 := range vault.Secrets {
+		content, err := base64.StdEncoding.DecodeString(secret64)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+				continue
+			}
+			if index := strings.IndexAny(line, "=:"); index > 0 && strings.TrimSpace(line[:index]) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the problems with the references: missing vaults and missing keys.  If unused is true,
+// the vaults that are not referenced are reported as well.
+func checkVaultReferences(references []vaultReference, vaults []serverapi.Vault, unused bool) (problems []string) {
+	vaultMap := make(map[string]serverapi.Vault)
+	for _, vault := range vaults {
+		vaultMap[vault.Name] = vault
+	}
+
+	used := make(map[string]bool)
+	for _, reference := range references {
+		used[reference.vault] = true
+		vault, found := vaultMap[reference.vault]
+		if !found {
+			problems = append(problems, reference.file+": Vault "+reference.vault+" does not exist")
+			continue
+		}
+		for _, key := range reference.keys {
+			if !vaultHasKey(vault, key) {
+				problems = append(problems, reference.file+": Key "+key+" not found in vault "+reference.vault)
+			}
+		}
+	}
+
+	if unused {
+		var names []string
+		for name := range vaultMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !used[name] {
+				problems = append(problems, "Vault "+name+" is not used")
+			}
+		}
+	}
+	return problems
+}
+
+// Lists which files reference each vault, or just the given vault.  In check mode, the missing vaults and keys
+// and the unused vaults are reported, and an error is returned if there are any.
+func Usage(vaultName string, check bool, config *configuration.ConfigurationClass) (output string, err error) {
+	ac, err := auroraconfig.GetAuroraConfig(config)
+	if err != nil {
+		return "", err
+	}
+	vaults, err := auroraconfig.GetVaultsArray(config)
+	if err != nil {
+		return "", err
+	}
+	references, err := getVaultReferences(ac)
+	if err != nil {
+		return "", err
+	}
+
+	if vaultName != "" {
+		var filtered []vaultReference
+		for _, reference := range references {
+			if reference.vault == vaultName {
+				filtered = append(filtered, reference)
+			}
+		}
+		references = filtered
+	}
+
+	if check {
+		var filteredVaults []serverapi.Vault
+		for _, vault := range vaults {
+			if vaultName == "" || vault.Name == vaultName {
+				filteredVaults = append(filteredVaults, vault)
+			}
+		}
+		problems := checkVaultReferences(references, filteredVaults, true)
+		if len(problems) == 0 {
+			return "No problems found\n", nil
+		}
+		return strings.Join(problems, "\n") + "\n", errors.New(strconv.Itoa(len(problems)) + " problems found")
+	}
+
+	var vaultColumn, fileColumn, keyColumn []string
+	for _, reference := range references {
+		vaultColumn = append(vaultColumn, reference.vault)
+		fileColumn = append(fileColumn, reference.file)
+		keyColumn = append(keyColumn, strings.Join(reference.keys, ","))
+	}
+	headers := []string{"VAULT", "FILE", "KEYS"}
+	return printutil.FormatTable(headers, vaultColumn, fileColumn, keyColumn), nil
+}
+
+// Checks the vault references in the files that make up the given env/app deployments,
+// and returns an error listing the missing vaults and keys
+func CheckDeployments(auroraConfig serverapi.AuroraConfig, deployments []string, config *configuration.ConfigurationClass) (err error) {
+	files := make(map[string]bool)
+	for _, deployment := range deployments {
+		parts := strings.Split(deployment, "/")
+		if len(parts) != 2 {
+			continue
+		}
+		for _, filename := range []string{"about.json", parts[1] + ".json", parts[0] + "/about.json", deployment + ".json"} {
+			files[filename] = true
+		}
+	}
+
+	references, err := getVaultReferences(auroraConfig)
+	if err != nil {
+		return err
+	}
+	var used []vaultReference
+	for _, reference := range references {
+		if files[reference.file] {
+			used = append(used, reference)
+		}
+	}
+	if len(used) == 0 {
+		return nil
+	}
+
+	vaults, err := auroraconfig.GetVaultsArray(config)
+	if err != nil {
+		return err
+	}
+	problems := checkVaultReferences(used, vaults, false)
+	if len(problems) > 0 {
+		return errors.New("Vault check failed:\n\t" + strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
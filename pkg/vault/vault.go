@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 
 	"github.com/skatteetaten/ao/pkg/auroraconfig"
 	"github.com/skatteetaten/ao/pkg/configuration"
+	"github.com/skatteetaten/ao/pkg/cryptutil"
 	"github.com/skatteetaten/ao/pkg/fileutil"
 	"github.com/skatteetaten/ao/pkg/jsonutil"
 	"github.com/skatteetaten/ao/pkg/printutil"
@@ -144,8 +146,18 @@ func listPermissions(user []string, group []string) (output string, err error) {
 	return output, nil
 }
 
+// Changes the permissions on a vault.  Each list may contain several users or groups.
+// If set is true, the permissions are replaced by the added users and groups.
+// If no changes are given, the permissions are listed.
 func Permissions(vaultName string, config *configuration.ConfigurationClass,
-	addGroup string, removeGroup string, addUser string, removeUser string) (output string, err error) {
+	addGroups []string, removeGroups []string, addUsers []string, removeUsers []string, set bool) (output string, err error) {
+
+	if set && (len(removeGroups) > 0 || len(removeUsers) > 0) {
+		return "", errors.New("Error: --set can not be combined with --remove-group or --remove-user")
+	}
+	if set && len(addGroups) == 0 && len(addUsers) == 0 {
+		return "", errors.New("Error: --set requires at least one group or user")
+	}
 
 	var vault serverapi.Vault
 	vault, err = auroraconfig.GetVault(vaultName, config)
@@ -153,35 +165,39 @@ func Permissions(vaultName string, config *configuration.ConfigurationClass,
 		return "", err
 	}
 
-	// Do your stuff
-	if addGroup != "" {
-		vault.Permissions.Groups = appendNoDuplicate(vault.Permissions.Groups, addGroup)
+	if !set && len(addGroups) == 0 && len(addUsers) == 0 && len(removeGroups) == 0 && len(removeUsers) == 0 {
+		// No flags given, list permissions
+		output, err = listPermissions(vault.Permissions.Users, vault.Permissions.Groups)
+		return output, err
 	}
 
-	if addUser != "" {
-		vault.Permissions.Users = appendNoDuplicate(vault.Permissions.Users, addUser)
+	if set {
+		vault.Permissions.Groups = nil
+		vault.Permissions.Users = nil
 	}
 
-	if removeGroup != "" {
-		vault.Permissions.Groups, err = remove(vault.Permissions.Groups, removeGroup)
+	for _, group := range addGroups {
+		vault.Permissions.Groups = appendNoDuplicate(vault.Permissions.Groups, group)
+	}
+
+	for _, user := range addUsers {
+		vault.Permissions.Users = appendNoDuplicate(vault.Permissions.Users, user)
+	}
+
+	for _, group := range removeGroups {
+		vault.Permissions.Groups, err = remove(vault.Permissions.Groups, group)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	if removeUser != "" {
-		vault.Permissions.Users, err = remove(vault.Permissions.Users, removeUser)
+	for _, user := range removeUsers {
+		vault.Permissions.Users, err = remove(vault.Permissions.Users, user)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	if addGroup == "" && addUser == "" && removeGroup == "" && removeUser == "" {
-		// No flags given, list permissions
-		output, err = listPermissions(vault.Permissions.Users, vault.Permissions.Groups)
-		return output, err
-	}
-
 	// Save
 	output, err = auroraconfig.PutVault(vaultName, vault, "", config)
 	if err != nil {
@@ -191,6 +207,72 @@ func Permissions(vaultName string, config *configuration.ConfigurationClass,
 	return output, nil
 }
 
+// Lists the permissions of all vaults in the affiliation as a matrix, with one row per vault
+// and one column per group and user
+func PermissionsMatrix(config *configuration.ConfigurationClass) (output string, err error) {
+	vaults, err := auroraconfig.GetVaultsArray(config)
+	if err != nil {
+		return "", err
+	}
+	return formatPermissionsMatrix(vaults), nil
+}
+
+func formatPermissionsMatrix(vaults []serverapi.Vault) (output string) {
+	sort.Slice(vaults, func(i, j int) bool {
+		return vaults[i].Name < vaults[j].Name
+	})
+
+	var groups, users []string
+	for _, vault := range vaults {
+		for _, group := range vault.Permissions.Groups {
+			groups = appendNoDuplicate(groups, group)
+		}
+		for _, user := range vault.Permissions.Users {
+			users = appendNoDuplicate(users, user)
+		}
+	}
+	sort.Strings(groups)
+	sort.Strings(users)
+
+	headers := []string{"VAULT"}
+	columns := [][]string{{}}
+	for _, group := range groups {
+		headers = append(headers, "group:"+group)
+		columns = append(columns, []string{})
+	}
+	for _, user := range users {
+		headers = append(headers, "user:"+user)
+		columns = append(columns, []string{})
+	}
+
+	var unrestricted []string
+	for _, vault := range vaults {
+		columns[0] = append(columns[0], vault.Name)
+		for i, group := range groups {
+			columns[i+1] = append(columns[i+1], accessMark(vault.Permissions.Groups, group))
+		}
+		for i, user := range users {
+			columns[len(groups)+i+1] = append(columns[len(groups)+i+1], accessMark(vault.Permissions.Users, user))
+		}
+		if len(vault.Permissions.Groups) == 0 && len(vault.Permissions.Users) == 0 {
+			unrestricted = append(unrestricted, vault.Name)
+		}
+	}
+
+	output = printutil.FormatTable(headers, columns...)
+	if len(unrestricted) > 0 {
+		output += "\nVaults without permissions: " + strings.Join(unrestricted, ", ") + "\n"
+	}
+	return output
+}
+
+func accessMark(list []string, value string) string {
+	if _, err := getIndex(list, value); err == nil {
+		return "X"
+	}
+	return "-"
+}
+
 func ImportVaults(catalogName string, config *configuration.ConfigurationClass) (output string, err error) {
 	vaults, err := vaultsFolder2VaultsArray(catalogName)
 	if err != nil {
@@ -246,8 +328,8 @@ func secretsFolder2Vault(folderName string) (vault serverapi.Vault, err error) {
 	for _, f := range files {
 		absolutePath := filepath.Join(folderName, f.Name())
 		if fileutil.IsLegalFileFolder(absolutePath) == fileutil.SpecIsFile {
-			// Read file content
-			secretContent, err := ioutil.ReadFile(absolutePath)
+			// Read file content, encrypted files are decrypted
+			secretContent, secretName, err := cryptutil.ReadSecretFile(absolutePath)
 			if err != nil {
 				return vault, err
 			}
@@ -267,8 +349,11 @@ func secretsFolder2Vault(folderName string) (vault serverapi.Vault, err error) {
 					return vault, err
 				}
 			} else {
+				if _, exists := vault.Secrets[secretName]; exists {
+					err = errors.New("Secret " + secretName + " is given both encrypted and in plaintext in " + folderName)
+					return vault, err
+				}
 				secretContent64 := base64.StdEncoding.EncodeToString(secretContent)
-				secretName := filepath.Base(absolutePath)
 				vault.Secrets[secretName] = secretContent64
 				vaultIndex++
 			}
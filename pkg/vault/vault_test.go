@@ -2,8 +2,15 @@ package vault
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/skatteetaten/ao/pkg/printutil"
+	"github.com/skatteetaten/ao/pkg/serverapi"
 )
 
 const expectedFolderCount = 2
@@ -50,3 +57,155 @@ func TestSecretFolder2Vault(t *testing.T) {
 	}
 
 }
+
+func TestVault2SecretsFolder(t *testing.T) {
+	folderName, err := ioutil.TempDir("", "ao_vault_export_")
+	if err != nil {
+		t.Fatalf("Error creating temporary folder: %v", err.Error())
+	}
+	defer os.RemoveAll(folderName)
+
+	var vault serverapi.Vault
+	vault.Name = "Vault1"
+	vault.Permissions.Groups = []string{"team"}
+	vault.Secrets = map[string]string{
+		"latest.properties": base64.StdEncoding.EncodeToString([]byte("user=admin\npassword=secret\n")),
+		"binary":            base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 255}),
+	}
+
+	if err := vault2SecretsFolder(vault, folderName); err != nil {
+		t.Fatalf("Error in vault2SecretsFolder: %v", err.Error())
+	}
+
+	info, err := os.Stat(filepath.Join(folderName, "Vault1", "latest.properties"))
+	if err != nil {
+		t.Fatalf("Error reading exported secret: %v", err.Error())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("vault2SecretsFolder wrote secret with mode %v, expected %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+
+	imported, err := secretsFolder2Vault(filepath.Join(folderName, "Vault1"))
+	if err != nil {
+		t.Fatalf("Error in secretsFolder2Vault: %v", err.Error())
+	}
+	if imported.Name != vault.Name || !reflect.DeepEqual(imported.Permissions, vault.Permissions) || !reflect.DeepEqual(imported.Secrets, vault.Secrets) {
+		t.Errorf("Exported vault did not import unchanged, expected %v, got %v", vault, imported)
+	}
+
+	if err := vault2SecretsFolder(vault, folderName); err == nil {
+		t.Errorf("vault2SecretsFolder did not fail on existing folder")
+	}
+}
+
+func TestDiffVault(t *testing.T) {
+	var local, remote serverapi.Vault
+	local.Secrets = map[string]string{"same": "YQ==", "changed": "Yg==", "added": "Yw=="}
+	remote.Secrets = map[string]string{"same": "YQ==", "changed": "ZA==", "removed": "ZQ=="}
+
+	diff := diffVault(local, remote)
+	if !reflect.DeepEqual(diff.added, []string{"added"}) || !reflect.DeepEqual(diff.changed, []string{"changed"}) ||
+		!reflect.DeepEqual(diff.removed, []string{"removed"}) {
+		t.Errorf("diffVault returned unexpected result: %v", diff)
+	}
+	if diff.permissionsChanged {
+		t.Errorf("diffVault reported changed permissions without a local permissions file")
+	}
+
+	local.Permissions.Groups = []string{"b", "a"}
+	remote.Permissions.Groups = []string{"a", "b"}
+	if diffVault(local, remote).permissionsChanged {
+		t.Errorf("diffVault reported changed permissions for the same groups in another order")
+	}
+	remote.Permissions.Groups = []string{"a"}
+	if !diffVault(local, remote).permissionsChanged {
+		t.Errorf("diffVault did not report changed permissions")
+	}
+
+	if !diffVault(remote, remote).isEmpty() {
+		t.Errorf("diffVault reported changes between equal vaults")
+	}
+}
+
+func TestFormatPermissionsMatrix(t *testing.T) {
+	vaults := make([]serverapi.Vault, 3)
+	vaults[0].Name = "db"
+	vaults[0].Permissions.Groups = []string{"dba", "dev"}
+	vaults[1].Name = "api"
+	vaults[1].Permissions.Groups = []string{"dev"}
+	vaults[1].Permissions.Users = []string{"ops1"}
+	vaults[2].Name = "open"
+
+	expected := printutil.FormatTable([]string{"VAULT", "group:dba", "group:dev", "user:ops1"},
+		[]string{"api", "db", "open"},
+		[]string{"-", "X", "-"},
+		[]string{"X", "X", "-"},
+		[]string{"X", "-", "-"}) + "\nVaults without permissions: open\n"
+
+	if output := formatPermissionsMatrix(vaults); output != expected {
+		t.Errorf("formatPermissionsMatrix returned unexpected result, expected:\n%v\ngot:\n%v", expected, output)
+	}
+}
+
+func TestCheckVaultReferences(t *testing.T) {
+	ac := serverapi.AuroraConfig{
+		Files: map[string]json.RawMessage{
+			"about.json":       json.RawMessage(`{"cluster": "utv"}`),
+			"api.json":         json.RawMessage(`{"secretVault": "api"}`),
+			"test/api.json":    json.RawMessage(`{"secretVault": {"name": "api", "keys": ["db.password", "token", "missing"]}}`),
+			"test/worker.json": json.RawMessage(`{"secretVault": "gone"}`),
+			"prod/api.json":    json.RawMessage(`{"secretFolder": "api", "secretVault": "api"}`),
+			"prod/worker.json": json.RawMessage(`{"secretFolder": "legacy"}`),
+			"prod/batch.json":  json.RawMessage(`{"secretFolder": "removed"}`),
+		},
+	}
+	references, err := getVaultReferences(ac)
+	if err != nil {
+		t.Fatalf("Error in getVaultReferences: %v", err.Error())
+	}
+	if len(references) != 6 || references[0].file != "api.json" || references[1].file != "prod/api.json" ||
+		references[3].vault != "gone" || references[4].vault != "legacy" || references[5].vault != "removed" {
+		t.Errorf("getVaultReferences returned unexpected result: %v", references)
+	}
+
+	vaults := make([]serverapi.Vault, 3)
+	vaults[0].Name = "api"
+	vaults[0].Secrets = map[string]string{
+		"latest.properties": base64.StdEncoding.EncodeToString([]byte("# comment\ndb.password = secret\n")),
+		"token":             base64.StdEncoding.EncodeToString([]byte("abc")),
+	}
+	vaults[1].Name = "unused"
+	vaults[2].Name = "legacy"
+
+	expected := []string{
+		"test/api.json: Key missing not found in vault api",
+		"test/worker.json: Vault gone does not exist",
+		"prod/batch.json: Vault removed does not exist",
+		"Vault unused is not used",
+	}
+	if problems := checkVaultReferences(references, vaults, true); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("checkVaultReferences returned unexpected result, expected %v, got %v", expected, problems)
+	}
+}
+
+func TestRewriteVaultReferences(t *testing.T) {
+	content := `{
+  "secretFolder" : "old",
+  "secretVault": {"name": "old", "keys": ["a"]},
+  "other": "old",
+  "certificate": "older"
+}`
+	expected := `{
+  "secretFolder" : "new",
+  "secretVault": {"name": "new", "keys": ["a"]},
+  "other": "old",
+  "certificate": "older"
+}`
+	rewritten, changed := rewriteVaultReferences(content, "old", "new")
+	if !changed || rewritten != expected {
+		t.Errorf("rewriteVaultReferences returned unexpected result:\n%v", rewritten)
+	}
+	if _, changed := rewriteVaultReferences(`{"secretVault": "older"}`, "old", "new"); changed {
+		t.Errorf("rewriteVaultReferences changed a reference to another vault")
+	}
+}